@@ -4,11 +4,17 @@ import (
 	"context"
 	"flag"
 	"log"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	// Now entries show the local time of the author without relying on the
+	// system timezone database
+	_ "time/tzdata"
 
 	"github.com/bertinatto/journal3/http"
+	"github.com/bertinatto/journal3/mail"
 	"github.com/bertinatto/journal3/sqlite"
 	"k8s.io/klog/v2"
 )
@@ -16,17 +22,46 @@ import (
 const (
 	defaultDataFile = "data.db"
 	defaultAddress  = "localhost:8080"
+
+	sessionKeysEnv  = "JOURNAL3_SESSION_KEYS"
+	smtpPasswordEnv = "JOURNAL3_SMTP_PASSWORD"
 )
 
 func main() {
 	klog.InitFlags(nil)
 	file := flag.String("file", defaultDataFile, "file where data will persist")
 	domain := flag.String("domain", "", "domain")
+	baseURL := flag.String("base-url", "", "absolute URL of the site, used in links sent by email (defaults to https://domain)")
 	addr := flag.String("listen", defaultAddress, "ip:port")
+	author := flag.String("author", "", "name of the author shown in feeds")
+	behindProxy := flag.Bool("behind-proxy", false, "take client addresses from X-Forwarded-For, only safe when every request goes through a reverse proxy")
+	sessionKeys := flag.String("session-keys", "", "comma-separated session secrets, newest first (defaults to $"+sessionKeysEnv+")")
+	sessionKeysFile := flag.String("session-keys-file", "", "file with one session secret per line, newest first")
+	signup := flag.String("signup", http.SignupModeOpen, "who may sign up: open, invite or closed")
+	smtpAddr := flag.String("smtp", "", "host:port of the SMTP server used to send emails")
+	smtpUsername := flag.String("smtp-username", "", "SMTP username, the password is read from $"+smtpPasswordEnv)
+	mailFrom := flag.String("mail-from", "", "sender address of emails (defaults to no-reply@domain)")
+	mailDir := flag.String("mail-dir", "", "without -smtp, write emails to this directory instead of logging them")
 	flag.Parse()
 
+	if !http.IsValidSignupMode(*signup) {
+		klog.Fatalf("Invalid signup mode %q", *signup)
+	}
+
+	if *baseURL != "" {
+		u, err := url.Parse(*baseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			klog.Fatalf("Invalid base URL %q, expected something like https://example.com", *baseURL)
+		}
+	}
+
+	secrets, err := loadSessionSecrets(*sessionKeys, *sessionKeysFile)
+	if err != nil {
+		klog.Fatal(err)
+	}
+
 	dir := filepath.Dir(*file)
-	_, err := os.Stat(dir)
+	_, err = os.Stat(dir)
 	if os.IsNotExist(err) {
 		log.Printf("isnotexist")
 		err := os.Mkdir(dir, 0750)
@@ -48,11 +83,39 @@ func main() {
 
 	s := http.NewServer()
 	s.Domain = *domain
+	s.PublicURL = strings.TrimSuffix(*baseURL, "/")
 	s.Addr = *addr
+	s.Author = *author
+	s.BehindProxy = *behindProxy
+	s.SignupMode = *signup
 	s.PageService = sqlite.NewPageService(db)
+	s.MenuService = sqlite.NewMenuService(db)
 	s.JournalService = sqlite.NewJournalService(db)
 	s.NowService = sqlite.NewNowService(db)
 	s.UserService = sqlite.NewUserService(db)
+	s.SearchService = sqlite.NewSearchService(db)
+	s.SessionService = sqlite.NewSessionService(db)
+	s.SessionStore = http.NewSessionStore(s.SessionService, secrets...)
+	s.SessionStore.Options.Secure = s.TLS()
+	s.InvitationService = sqlite.NewInvitationService(db)
+	s.FailedLoginService = sqlite.NewFailedLoginService(db)
+	s.PasswordResetService = sqlite.NewPasswordResetService(db)
+
+	from := *mailFrom
+	if from == "" && s.TLS() {
+		from = "no-reply@" + s.Domain
+	} else if from == "" {
+		from = "no-reply@localhost"
+	}
+	if *smtpAddr != "" {
+		s.Mailer = mail.NewSMTPMailer(*smtpAddr, *smtpUsername, os.Getenv(smtpPasswordEnv), from)
+	} else {
+		klog.Warning("No SMTP server configured, emails won't be sent")
+		s.Mailer = mail.NewFileMailer(*mailDir, from)
+	}
+	if s.PublicURL == "" && !s.TLS() {
+		klog.Warning("No -base-url or -domain configured, password reset emails won't be sent")
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	c := make(chan os.Signal, 1)
@@ -79,6 +142,56 @@ func main() {
 		http.ListenAndServeDebug()
 	}()
 
+	// Publish scheduled posts in the background
+	publisher := sqlite.NewPublisher(db)
+	publisherDone := make(chan struct{})
+	go func() {
+		defer close(publisherDone)
+		publisher.Run(ctx)
+	}()
+
+	// Delete expired sessions and old failed logins in the background
+	cleanupDone := make(chan struct{})
+	go func() {
+		defer close(cleanupDone)
+		s.Cleanup(ctx)
+	}()
+
 	// Wait for CTRL-C
 	<-ctx.Done()
+	<-publisherDone
+	<-cleanupDone
+}
+
+// loadSessionSecrets reads the session secrets from the key file, the flag
+// or the environment, in that order. Without any secret a random one is
+// generated, so sessions won't survive a restart.
+func loadSessionSecrets(keys, file string) ([]string, error) {
+	if file != "" {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		keys = strings.ReplaceAll(string(buf), "\n", ",")
+	} else if keys == "" {
+		keys = os.Getenv(sessionKeysEnv)
+	}
+
+	var secrets []string
+	for _, key := range strings.Split(keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			secrets = append(secrets, key)
+		}
+	}
+
+	if len(secrets) == 0 {
+		klog.Warning("No session keys configured, generating a random one")
+		secret, err := http.GenerateSessionSecret()
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, nil
 }
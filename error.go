@@ -10,6 +10,8 @@ const (
 	EBADINPUT      = "bad_input"
 	EINTERNAL      = "internal"
 	ENOTAUTHORIZED = "not_authorized"
+	EFORBIDDEN     = "forbidden"
+	ETOOMANY       = "too_many_requests"
 )
 
 type Error struct {
@@ -5,10 +5,12 @@ go 1.16
 require (
 	github.com/gomarkdown/markdown v0.0.0-20210208175418-bda154fe17d8
 	github.com/gorilla/mux v1.8.0
+	github.com/gorilla/securecookie v1.1.1
 	github.com/gorilla/sessions v1.2.1
 	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/prometheus/client_golang v1.10.0
 	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
 	golang.org/x/sys v0.0.0-20210423185535-09eb48e85fd7 // indirect
 	k8s.io/klog/v2 v2.5.0
+	rsc.io/qr v0.2.0
 )
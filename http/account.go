@@ -0,0 +1,139 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+type accountData struct {
+	User *journal.User
+}
+
+func (s *Server) handleAccountView(w http.ResponseWriter, r *http.Request) {
+	err := render(w, r, "account", &accountData{User: journal.UserFromContext(r.Context())})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleAccountUpdate(w http.ResponseWriter, r *http.Request) {
+	user := journal.UserFromContext(r.Context())
+
+	err := r.ParseForm()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"})
+		return
+	}
+
+	name := r.Form.Get("name")
+	email := r.Form.Get("email")
+	if name == "" || email == "" {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing parameters: name and email are required"})
+		return
+	}
+
+	updated := &journal.UserUpdate{Name: &name}
+	if email != user.Email {
+		// The email is where password resets go, so changing it must not be
+		// possible with just a stolen session
+		err = checkPassword(r, user)
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+
+		err = journal.ValidateEmail(email)
+		if err != nil {
+			Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid email: %v", err)})
+			return
+		}
+		updated.Email = &email
+	}
+
+	err = s.UserService.UpdateUser(r.Context(), user.ID, updated)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/account", http.StatusFound)
+}
+
+func (s *Server) handleAccountPassword(w http.ResponseWriter, r *http.Request) {
+	user := journal.UserFromContext(r.Context())
+
+	err := checkPassword(r, user)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	password := r.Form.Get("new_password")
+	if password != r.Form.Get("confirm") {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Passwords don't match"})
+		return
+	}
+
+	err = journal.ValidatePassword(password)
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid password: %v", err)})
+		return
+	}
+
+	hashed, err := hashPassword(password)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = s.UserService.UpdateUser(r.Context(), user.ID, &journal.UserUpdate{Password: &hashed})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Sign out every other device, in case the old password leaked
+	current, _ := s.SessionStore.Get(r, sessionCookie)
+	err = s.deleteUserSessions(r.Context(), user.ID, current.ID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/account", http.StatusFound)
+}
+
+func (s *Server) handleAccountDeleteConfirm(w http.ResponseWriter, r *http.Request) {
+	err := render(w, r, "deleteaccount", &accountData{User: journal.UserFromContext(r.Context())})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleAccountDelete(w http.ResponseWriter, r *http.Request) {
+	user := journal.UserFromContext(r.Context())
+
+	err := checkPassword(r, user)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = s.UserService.DeleteUser(r.Context(), user.ID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// The session is already gone from the store, drop the cookie too
+	session, _ := s.SessionStore.Get(r, sessionCookie)
+	session.Options.MaxAge = -1
+	session.Values = map[interface{}]interface{}{}
+	session.Save(r, w)
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
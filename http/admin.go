@@ -0,0 +1,201 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	journal "github.com/bertinatto/journal3"
+	"github.com/gorilla/mux"
+)
+
+const usersPerPage = 20
+
+type adminUsersData struct {
+	Users      []*journal.User
+	Search     string
+	N          int
+	Pagination Pagination
+}
+
+type adminUserData struct {
+	User  *journal.User
+	Roles []string
+
+	// ResetSent is set right after a password reset is forced
+	ResetSent bool
+}
+
+func (s *Server) handleAdminUsers(w http.ResponseWriter, r *http.Request) {
+	page := pageFromRequest(r)
+	filter := &journal.UserFilter{
+		Offset: (page - 1) * usersPerPage,
+		Limit:  usersPerPage,
+	}
+
+	search := r.URL.Query().Get("q")
+	if search != "" {
+		filter.Search = &search
+	}
+
+	users, n, err := s.UserService.FindUsers(r.Context(), filter)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "adminusers", &adminUsersData{
+		Users:      users,
+		Search:     search,
+		N:          n,
+		Pagination: newPagination(page, usersPerPage, n),
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+// adminUserFromVars returns the user whose ID is in the route.
+func (s *Server) adminUserFromVars(r *http.Request) (*journal.User, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, &journal.Error{Code: journal.EBADINPUT, Message: "Invalid user"}
+	}
+	return s.UserService.FindUserByID(r.Context(), id)
+}
+
+func (s *Server) renderAdminUser(w http.ResponseWriter, r *http.Request, user *journal.User, resetSent bool) {
+	err := render(w, r, "adminuser", &adminUserData{
+		User:      user,
+		Roles:     []string{journal.RoleReader, journal.RoleEditor, journal.RoleAdmin},
+		ResetSent: resetSent,
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleAdminUser(w http.ResponseWriter, r *http.Request) {
+	user, err := s.adminUserFromVars(r)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	s.renderAdminUser(w, r, user, false)
+}
+
+func (s *Server) handleAdminUserUpdate(w http.ResponseWriter, r *http.Request) {
+	user, err := s.adminUserFromVars(r)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"})
+		return
+	}
+
+	role := r.Form.Get("role")
+	if !journal.IsValidRole(role) {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Invalid role"})
+		return
+	}
+	disabled := r.Form.Get("disabled") != ""
+
+	err = s.UserService.UpdateUser(r.Context(), user.ID, &journal.UserUpdate{
+		Role:     &role,
+		Disabled: &disabled,
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Sessions of disabled users are ignored anyway, but don't keep them
+	if disabled {
+		err = s.deleteUserSessions(r.Context(), user.ID, "")
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+	}
+
+	http.Redirect(w, r, "/admin/users/"+strconv.Itoa(user.ID), http.StatusFound)
+}
+
+// handleAdminUserReset replaces the user's password with a random one and
+// emails them a link to choose a new one.
+func (s *Server) handleAdminUserReset(w http.ResponseWriter, r *http.Request) {
+	user, err := s.adminUserFromVars(r)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Send the reset first, so a failed email doesn't lock the user out
+	err = s.sendPasswordReset(r, user)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Nobody knows this password, so the old one stops working right away
+	password, err := generateToken()
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+	hashed, err := hashPassword(password)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = s.UserService.UpdateUser(r.Context(), user.ID, &journal.UserUpdate{Password: &hashed})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = s.deleteUserSessions(r.Context(), user.ID, "")
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	s.renderAdminUser(w, r, user, true)
+}
+
+func (s *Server) handleAdminUserDeleteConfirm(w http.ResponseWriter, r *http.Request) {
+	user, err := s.adminUserFromVars(r)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "adminuserdelete", user)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleAdminUserDelete(w http.ResponseWriter, r *http.Request) {
+	user, err := s.adminUserFromVars(r)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = s.UserService.DeleteUser(r.Context(), user.ID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/users", http.StatusFound)
+}
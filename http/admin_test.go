@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+func TestAdminUserReset(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.PublicURL = "https://journal.example.com"
+	mailer := s.Mailer.(*testMailer)
+
+	createUser(t, s, "Ann", "ann@example.com", "secret1", journal.RoleAdmin)
+	bob := createUser(t, s, "Bob", "bob@example.com", "secret2", journal.RoleReader)
+	token, cookies := fetchCSRFToken(t, s, "/settings", login(t, s, "ann@example.com", "secret1"))
+	target := fmt.Sprintf("/admin/users/%d/reset", bob.ID)
+	form := url.Values{csrfFormField: {token}}
+
+	// The password is kept when the email can't be sent
+	mailer.err = errors.New("connection refused")
+	resp, _ := serve(t, s, formRequest("POST", target, form, cookies))
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("got status %d with a failing mailer, expected %d", resp.StatusCode, http.StatusInternalServerError)
+	}
+	user, err := s.UserService.FindUserByID(context.Background(), bob.ID)
+	if err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	if comparePassword(user, "secret2") != nil {
+		t.Error("the password was changed although the email wasn't sent")
+	}
+
+	mailer.err = nil
+	resp, body := serve(t, s, formRequest("POST", target, form, cookies))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, expected %d: %s", resp.StatusCode, http.StatusOK, body)
+	}
+	if len(mailer.messages) != 1 || mailer.messages[0].To != "bob@example.com" {
+		t.Fatalf("got %d messages, expected one to bob@example.com", len(mailer.messages))
+	}
+	user, err = s.UserService.FindUserByID(context.Background(), bob.ID)
+	if err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	if comparePassword(user, "secret2") == nil {
+		t.Error("the old password still works after the reset")
+	}
+}
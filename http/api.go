@@ -0,0 +1,582 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	journal "github.com/bertinatto/journal3"
+	"github.com/gorilla/mux"
+)
+
+type postListResponse struct {
+	Posts []*journal.Post `json:"posts"`
+	N     int             `json:"n"`
+}
+
+type pageListResponse struct {
+	Pages []*journal.Page `json:"pages"`
+	N     int             `json:"n"`
+}
+
+type userListResponse struct {
+	Users []*journal.User `json:"users"`
+	N     int             `json:"n"`
+}
+
+// userRequest is the body accepted when creating or updating users. Unlike
+// journal.User, it carries the plain text password. Changing the email or
+// the password of an account requires its current password.
+type userRequest struct {
+	Name            *string `json:"name"`
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	CurrentPassword *string `json:"currentPassword"`
+	Role            *string `json:"role"`
+}
+
+func (s *Server) handleAPIAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if journal.UserIDFromContext(r.Context()) > 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ErrorJSON(w, r, &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Authentication required"})
+	})
+}
+
+func (s *Server) handleAPINotFound(w http.ResponseWriter, r *http.Request) {
+	ErrorJSON(w, r, &journal.Error{Code: journal.ENOTFOUND, Message: "Endpoint not found"})
+}
+
+func (s *Server) handleAPIPostList(w http.ResponseWriter, r *http.Request) {
+	filter := &journal.PostFilter{}
+	query := r.URL.Query()
+
+	var err error
+	filter.Limit, filter.Offset, err = limitAndOffsetFromQuery(r)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	// Only authenticated users may list posts that aren't published
+	status := journal.PostStatusPublished
+	if v := query.Get("status"); v != "" && journal.UserIDFromContext(r.Context()) > 0 {
+		status = v
+	}
+	filter.Status = &status
+
+	if v := query.Get("tag"); v != "" {
+		filter.Tag = &v
+	}
+
+	posts, n, err := s.JournalService.FindPosts(r.Context(), filter)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &postListResponse{Posts: posts, N: n})
+}
+
+func (s *Server) handleAPIPostGet(w http.ResponseWriter, r *http.Request) {
+	post, err := s.JournalService.FindPostByPermalink(r.Context(), mux.Vars(r)["permalink"])
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	if post.Status == journal.PostStatusDraft && journal.UserIDFromContext(r.Context()) == 0 {
+		ErrorJSON(w, r, &journal.Error{Code: journal.ENOTFOUND, Message: "Post not found"})
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, post)
+}
+
+func (s *Server) handleAPIPostCreate(w http.ResponseWriter, r *http.Request) {
+	var post journal.Post
+	err := decodeJSON(r, &post)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	post.Permalink = strings.TrimSpace(post.Permalink)
+	post.Title = strings.TrimSpace(post.Title)
+	post.Content = strings.TrimSpace(post.Content)
+	if post.Permalink == "" || post.Title == "" || post.Content == "" {
+		ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing parameters: permalink, title and/or content"})
+		return
+	}
+
+	if post.Status == "" {
+		post.Status = journal.PostStatusDraft
+	}
+
+	err = post.Validate()
+	if err != nil {
+		ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid post: %v", err)})
+		return
+	}
+
+	_, err = s.JournalService.FindPostByPermalink(r.Context(), post.Permalink)
+	if err == nil {
+		ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Permalink already in use"})
+		return
+	} else if journal.ErrorCode(err) != journal.ENOTFOUND {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	if post.Tags == nil {
+		post.Tags = []string{}
+	}
+
+	err = s.JournalService.CreatePost(r.Context(), &post)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, &post)
+}
+
+func (s *Server) handleAPIPostUpdate(w http.ResponseWriter, r *http.Request) {
+	permalink := mux.Vars(r)["permalink"]
+
+	var updated journal.PostUpdate
+	err := decodeJSON(r, &updated)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	if v := updated.Status; v != nil && !journal.IsValidPostStatus(*v) {
+		ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid status: %s", *v)})
+		return
+	}
+
+	err = s.JournalService.UpdatePost(r.Context(), permalink, &updated)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	post, err := s.JournalService.FindPostByPermalink(r.Context(), permalink)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, post)
+}
+
+func (s *Server) handleAPIPostDelete(w http.ResponseWriter, r *http.Request) {
+	err := s.JournalService.DeletePost(r.Context(), mux.Vars(r)["permalink"])
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleAPIPageList(w http.ResponseWriter, r *http.Request) {
+	filter := &journal.PageFilter{}
+
+	var err error
+	filter.Limit, filter.Offset, err = limitAndOffsetFromQuery(r)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	pages, n, err := s.PageService.FindPages(r.Context(), filter)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &pageListResponse{Pages: pages, N: n})
+}
+
+func (s *Server) handleAPIPageGet(w http.ResponseWriter, r *http.Request) {
+	page, err := s.PageService.FindPageByName(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, page)
+}
+
+func (s *Server) handleAPIPageCreate(w http.ResponseWriter, r *http.Request) {
+	var page journal.Page
+	err := decodeJSON(r, &page)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	page.Name = strings.TrimSpace(page.Name)
+	page.Title = strings.TrimSpace(page.Title)
+	page.Content = strings.TrimSpace(page.Content)
+	if page.Content == "" {
+		ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing parameters: content"})
+		return
+	}
+
+	err = page.Validate()
+	if err != nil {
+		ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid page: %v", err)})
+		return
+	}
+
+	err = s.PageService.CreatePage(r.Context(), &page)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, &page)
+}
+
+func (s *Server) handleAPIPageUpdate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var updated journal.PageUpdate
+	err := decodeJSON(r, &updated)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	if updated.Title != nil && strings.TrimSpace(*updated.Title) == "" {
+		ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Invalid page: title is required"})
+		return
+	}
+
+	err = s.PageService.UpdatePage(r.Context(), name, &updated)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	page, err := s.PageService.FindPageByName(r.Context(), name)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, page)
+}
+
+func (s *Server) handleAPIPageDelete(w http.ResponseWriter, r *http.Request) {
+	err := s.PageService.DeletePage(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleAPINowGet(w http.ResponseWriter, r *http.Request) {
+	now, err := s.NowService.FindLatestNow(r.Context())
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, now)
+}
+
+func (s *Server) handleAPINowCreate(w http.ResponseWriter, r *http.Request) {
+	var now journal.Now
+	err := decodeJSON(r, &now)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	if now.FromLocation == "" || now.Content == "" {
+		ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing parameters: from_location and/or content"})
+		return
+	}
+
+	err = now.Validate()
+	if err != nil {
+		ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid location: %v", err)})
+		return
+	}
+
+	err = s.NowService.CreateNow(r.Context(), &now)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, &now)
+}
+
+func (s *Server) handleAPIUserList(w http.ResponseWriter, r *http.Request) {
+	filter := &journal.UserFilter{}
+
+	var err error
+	filter.Limit, filter.Offset, err = limitAndOffsetFromQuery(r)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	if v := r.URL.Query().Get("search"); v != "" {
+		filter.Search = &v
+	}
+
+	users, n, err := s.UserService.FindUsers(r.Context(), filter)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, &userListResponse{Users: users, N: n})
+}
+
+func (s *Server) handleAPIUserGet(w http.ResponseWriter, r *http.Request) {
+	id, err := s.ownUserIDFromVars(r)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	user, err := s.UserService.FindUserByID(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, user)
+}
+
+func (s *Server) handleAPIUserCreate(w http.ResponseWriter, r *http.Request) {
+	var req userRequest
+	err := decodeJSON(r, &req)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	if req.Name == nil || req.Email == nil || req.Password == nil {
+		ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing parameters: name, email and password are required"})
+		return
+	}
+
+	user := &journal.User{
+		Name:     *req.Name,
+		Email:    *req.Email,
+		Password: *req.Password,
+		Role:     journal.RoleReader,
+	}
+	if req.Role != nil {
+		user.Role = *req.Role
+	}
+
+	err = user.Validate()
+	if err != nil {
+		ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid user: %v", err)})
+		return
+	}
+
+	_, err = s.UserService.FindUserByEmail(r.Context(), user.Email)
+	if err == nil {
+		ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Email already in use"})
+		return
+	} else if journal.ErrorCode(err) != journal.ENOTFOUND {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	user.Password, err = hashPassword(user.Password)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	err = s.UserService.CreateUser(r.Context(), user)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, user)
+}
+
+func (s *Server) handleAPIUserUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := s.ownUserIDFromVars(r)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	var req userRequest
+	err = decodeJSON(r, &req)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	user := journal.UserFromContext(r.Context())
+	updated := &journal.UserUpdate{Name: req.Name}
+
+	// Same rules as the account page: the email is where password resets go
+	// and the password signs in, so neither can change with just a session
+	// or an API key
+	if (req.Email != nil && *req.Email != user.Email) || req.Password != nil {
+		if req.CurrentPassword == nil {
+			ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing parameter: currentPassword is required to change the email or password"})
+			return
+		}
+		err = comparePassword(user, *req.CurrentPassword)
+		if err != nil {
+			ErrorJSON(w, r, err)
+			return
+		}
+	}
+
+	if v := req.Email; v != nil && *v != user.Email {
+		err = journal.ValidateEmail(*v)
+		if err != nil {
+			ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid email: %v", err)})
+			return
+		}
+		updated.Email = v
+	}
+
+	if v := req.Role; v != nil {
+		if !user.HasRole(journal.RoleAdmin) {
+			ErrorJSON(w, r, &journal.Error{Code: journal.EFORBIDDEN, Message: "Only admins can change roles"})
+			return
+		}
+		if !journal.IsValidRole(*v) {
+			ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid role: %s", *v)})
+			return
+		}
+		updated.Role = v
+	}
+
+	if req.Password != nil {
+		err = journal.ValidatePassword(*req.Password)
+		if err != nil {
+			ErrorJSON(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid password: %v", err)})
+			return
+		}
+		password, err := hashPassword(*req.Password)
+		if err != nil {
+			ErrorJSON(w, r, err)
+			return
+		}
+		updated.Password = &password
+	}
+
+	err = s.UserService.UpdateUser(r.Context(), id, updated)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	// Sign out every other device, in case the old password leaked
+	if updated.Password != nil {
+		current, _ := s.SessionStore.Get(r, sessionCookie)
+		err = s.deleteUserSessions(r.Context(), id, current.ID)
+		if err != nil {
+			ErrorJSON(w, r, err)
+			return
+		}
+	}
+
+	user, err = s.UserService.FindUserByID(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, user)
+}
+
+func (s *Server) handleAPIUserDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := s.ownUserIDFromVars(r)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	err = s.UserService.DeleteUser(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownUserIDFromVars returns the user ID from the route, making sure it
+// belongs to the authenticated user unless they are an admin.
+func (s *Server) ownUserIDFromVars(r *http.Request) (int, error) {
+	id, err := userIDFromVars(r)
+	if err != nil {
+		return 0, err
+	}
+
+	user := journal.UserFromContext(r.Context())
+	if id != user.ID && !user.HasRole(journal.RoleAdmin) {
+		return 0, &journal.Error{Code: journal.EFORBIDDEN, Message: "Users can only modify their own account"}
+	}
+
+	return id, nil
+}
+
+func userIDFromVars(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, &journal.Error{Code: journal.EBADINPUT, Message: "Invalid user ID"}
+	}
+	return id, nil
+}
+
+func limitAndOffsetFromQuery(r *http.Request) (int, int, error) {
+	var limit, offset int
+	var err error
+
+	query := r.URL.Query()
+	if v := query.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return 0, 0, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid limit: %s", v)}
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid offset: %s", v)}
+		}
+	}
+
+	return limit, offset, nil
+}
+
+func isAPIRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/")
+}
+
+func decodeJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid JSON body: %v", err)}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+// apiClient is a browser session calling the API.
+type apiClient struct {
+	cookies []*http.Cookie
+	csrf    string
+}
+
+// apiRequest returns a JSON request carrying the session of the client.
+func apiRequest(method, target, body string, c apiClient) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if c.csrf != "" {
+		r.Header.Set(csrfHeader, c.csrf)
+	}
+	for _, cookie := range c.cookies {
+		r.AddCookie(cookie)
+	}
+	return r
+}
+
+// signIn logs in and returns a client carrying the session and its CSRF
+// token.
+func signIn(t *testing.T, s *Server, email, password string) apiClient {
+	t.Helper()
+
+	var c apiClient
+	c.csrf, c.cookies = fetchCSRFToken(t, s, "/settings", login(t, s, email, password))
+	return c
+}
+
+func TestAPIPermissions(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	err := s.JournalService.CreatePost(context.Background(), &journal.Post{
+		Permalink: "draft",
+		Title:     "Draft",
+		Content:   "Content",
+		Status:    journal.PostStatusDraft,
+	})
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	createUser(t, s, "Ann", "ann@example.com", "secret1", journal.RoleAdmin)
+	createUser(t, s, "Bob", "bob@example.com", "secret2", journal.RoleEditor)
+	carol := createUser(t, s, "Carol", "carol@example.com", "secret3", journal.RoleReader)
+
+	var visitor apiClient
+	visitor.csrf, visitor.cookies = fetchCSRFToken(t, s, "/login", nil)
+	admin := signIn(t, s, "ann@example.com", "secret1")
+	editor := signIn(t, s, "bob@example.com", "secret2")
+	reader := signIn(t, s, "carol@example.com", "secret3")
+
+	newPost := `{"permalink":"new","title":"New","content":"Content","status":"published"}`
+	for _, tc := range []struct {
+		name   string
+		method string
+		target string
+		body   string
+		client apiClient
+		status int
+	}{
+		{"anonymous lists posts", "GET", "/api/v1/posts", "", visitor, http.StatusOK},
+		{"anonymous reads a draft", "GET", "/api/v1/posts/draft", "", visitor, http.StatusNotFound},
+		{"anonymous creates a post", "POST", "/api/v1/posts", newPost, visitor, http.StatusUnauthorized},
+		{"anonymous lists users", "GET", "/api/v1/users", "", visitor, http.StatusUnauthorized},
+		{"reader creates a post", "POST", "/api/v1/posts", newPost, reader, http.StatusForbidden},
+		{"reader updates own account", "PATCH", fmt.Sprintf("/api/v1/users/%d", carol.ID), `{"name":"Caroline"}`, reader, http.StatusOK},
+		{"editor reads a draft", "GET", "/api/v1/posts/draft", "", editor, http.StatusOK},
+		{"editor creates a post", "POST", "/api/v1/posts", newPost, editor, http.StatusCreated},
+		{"editor lists users", "GET", "/api/v1/users", "", editor, http.StatusForbidden},
+		{"editor updates another account", "PATCH", fmt.Sprintf("/api/v1/users/%d", carol.ID), `{"name":"Mallory"}`, editor, http.StatusForbidden},
+		{"editor deletes another account", "DELETE", fmt.Sprintf("/api/v1/users/%d", carol.ID), "", editor, http.StatusForbidden},
+		{"admin lists users", "GET", "/api/v1/users", "", admin, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := serve(t, s, apiRequest(tc.method, tc.target, tc.body, tc.client))
+			if resp.StatusCode != tc.status {
+				t.Errorf("got status %d, expected %d: %s", resp.StatusCode, tc.status, body)
+			}
+		})
+	}
+
+	// Only the account owner could change it
+	user, err := s.UserService.FindUserByID(context.Background(), carol.ID)
+	if err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	if user.Name != "Caroline" {
+		t.Errorf("got name %q, expected only the owner's update to apply", user.Name)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	ann := createUser(t, s, "Ann", "ann@example.com", "secret1", journal.RoleEditor)
+	apiKey, err := generateToken()
+	if err != nil {
+		t.Fatalf("failed to generate API key: %v", err)
+	}
+	hashed := hashToken(apiKey)
+	err = s.UserService.UpdateUser(context.Background(), ann.ID, &journal.UserUpdate{APIKey: &hashed})
+	if err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"valid key", "Bearer " + apiKey, http.StatusCreated},
+		{"unknown key", "Bearer " + apiKey + "x", http.StatusUnauthorized},
+		{"not a bearer token", apiKey, http.StatusUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := apiRequest("POST", "/api/v1/posts", `{"permalink":"new","title":"New","content":"Content","status":"published"}`, apiClient{})
+			r.Header.Set("Authorization", tc.header)
+			resp, body := serve(t, s, r)
+			if resp.StatusCode != tc.status {
+				t.Errorf("got status %d, expected %d: %s", resp.StatusCode, tc.status, body)
+			}
+		})
+	}
+}
+
+func TestAPIUserUpdate(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	ann := createUser(t, s, "Ann", "ann@example.com", "secret1", journal.RoleReader)
+	c := signIn(t, s, "ann@example.com", "secret1")
+	other := signIn(t, s, "ann@example.com", "secret1")
+	target := fmt.Sprintf("/api/v1/users/%d", ann.ID)
+
+	for _, tc := range []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"email without current password", `{"email":"eve@example.com"}`, http.StatusBadRequest},
+		{"email with wrong password", `{"email":"eve@example.com","currentPassword":"wrong"}`, http.StatusUnauthorized},
+		{"invalid email", `{"email":"Eve <eve@example.com>","currentPassword":"secret1"}`, http.StatusBadRequest},
+		{"password without current password", `{"password":"secret2"}`, http.StatusBadRequest},
+		{"short password", `{"password":"short","currentPassword":"secret1"}`, http.StatusBadRequest},
+		{"same email without password", `{"name":"Annie","email":"ann@example.com"}`, http.StatusOK},
+		{"email", `{"email":"annie@example.com","currentPassword":"secret1"}`, http.StatusOK},
+		{"password", `{"password":"secret2","currentPassword":"secret1"}`, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := serve(t, s, apiRequest("PATCH", target, tc.body, c))
+			if resp.StatusCode != tc.status {
+				t.Errorf("got status %d, expected %d: %s", resp.StatusCode, tc.status, body)
+			}
+		})
+	}
+
+	user, err := s.UserService.FindUserByID(context.Background(), ann.ID)
+	if err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	if user.Name != "Annie" || user.Email != "annie@example.com" || comparePassword(user, "secret2") != nil {
+		t.Errorf("got user %q <%s>, expected the valid updates to apply", user.Name, user.Email)
+	}
+
+	// Changing the password signs out the other sessions only
+	resp, _ := serve(t, s, apiRequest("GET", target, "", other))
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got status %d for another session, expected %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	resp, _ = serve(t, s, apiRequest("GET", target, "", c))
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d for the current session, expected %d", resp.StatusCode, http.StatusOK)
+	}
+}
@@ -1,23 +1,81 @@
 package http
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	journal "github.com/bertinatto/journal3"
-	"github.com/gorilla/sessions"
+	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 	"k8s.io/klog/v2"
 )
 
-var (
-	key           = []byte("super-secret-key")
-	store         = sessions.NewCookieStore(key)
-	sessionCookie = "journal3-session"
+// Signup modes control who may create an account. Signup is always open
+// while there are no users, so the first admin can sign up.
+const (
+	SignupModeOpen   = "open"
+	SignupModeInvite = "invite"
+	SignupModeClosed = "closed"
 )
 
+// IsValidSignupMode reports whether mode is one of the known signup modes.
+func IsValidSignupMode(mode string) bool {
+	return mode == SignupModeOpen || mode == SignupModeInvite || mode == SignupModeClosed
+}
+
+type signupData struct {
+	Mode   string
+	Invite string
+}
+
+// signupMode returns the signup mode in effect for the current request.
+func (s *Server) signupMode(r *http.Request) (string, error) {
+	_, n, err := s.UserService.FindUsers(r.Context(), &journal.UserFilter{Limit: 1})
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return SignupModeOpen, nil
+	}
+
+	if s.SignupMode == "" {
+		return SignupModeOpen, nil
+	}
+	return s.SignupMode, nil
+}
+
 func (s *Server) handleSingUpView(w http.ResponseWriter, r *http.Request) {
-	err := tmpl.ExecuteTemplate(w, "signup", nil)
+	mode, err := s.signupMode(r)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	invite := r.URL.Query().Get("invite")
+	if mode == SignupModeInvite && invite != "" {
+		// Tell the user upfront rather than after they filled in the form
+		hash := hashToken(invite)
+		invitations, n, err := s.InvitationService.FindInvitations(r.Context(), &journal.InvitationFilter{TokenHash: &hash})
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+		if n == 0 || !invitations[0].IsValid(time.Now()) {
+			invite = ""
+		}
+	}
+
+	err = render(w, r, "signup", &signupData{
+		Mode:   mode,
+		Invite: invite,
+	})
 	if err != nil {
 		Error(w, r, err)
 		return
@@ -31,6 +89,21 @@ func (s *Server) handleSingUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	mode, err := s.signupMode(r)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	invite := r.Form.Get("invite")
+	if mode == SignupModeClosed {
+		Error(w, r, &journal.Error{Code: journal.EFORBIDDEN, Message: "Signup is closed"})
+		return
+	} else if mode == SignupModeInvite && invite == "" {
+		Error(w, r, &journal.Error{Code: journal.EFORBIDDEN, Message: "Signup requires an invitation"})
+		return
+	}
+
 	name := r.Form.Get("name")
 	email := r.Form.Get("email")
 	password := r.Form.Get("password")
@@ -41,14 +114,29 @@ func (s *Server) handleSingUp(w http.ResponseWriter, r *http.Request) {
 
 	_, err = s.UserService.FindUserByEmail(r.Context(), email)
 	if err == nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Email already in use"})
+		return
+	} else if journal.ErrorCode(err) != journal.ENOTFOUND {
+		Error(w, r, err)
+		return
+	}
+
+	// The first account to sign up administers the site
+	role := journal.RoleReader
+	_, n, err := s.UserService.FindUsers(r.Context(), &journal.UserFilter{Limit: 1})
+	if err != nil {
 		Error(w, r, err)
 		return
 	}
+	if n == 0 {
+		role = journal.RoleAdmin
+	}
 
 	u := &journal.User{
 		Name:     name,
 		Email:    email,
 		Password: password,
+		Role:     role,
 	}
 
 	err = u.Validate()
@@ -57,26 +145,34 @@ func (s *Server) handleSingUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Salt and hash the password using the bcrypt algorithm
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 8)
+	u.Password, err = hashPassword(password)
 	if err != nil {
-		Error(w, r, &journal.Error{Code: journal.EINTERNAL, Message: "Failed to process user"})
+		Error(w, r, err)
 		return
 	}
-	u.Password = string(hashedPassword)
 
-	err = s.UserService.CreateUser(r.Context(), u)
+	// The invitation is only burnt if the user is actually created
+	if mode == SignupModeInvite {
+		err = s.UserService.CreateUserWithInvitation(r.Context(), u, hashToken(invite))
+	} else {
+		err = s.UserService.CreateUser(r.Context(), u)
+	}
 	if err != nil {
 		Error(w, r, err)
 		return
 	}
 
-	session, err := store.Get(r, sessionCookie)
+	session, err := s.SessionStore.Get(r, sessionCookie)
 	if err != nil {
 		Error(w, r, err)
 		return
 	}
-	session.Options.MaxAge = 60 * 60 * 24 // 24 hours
+	err = s.SessionStore.Renew(r, session)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+	session.Options.MaxAge = sessionMaxAge
 	session.Values["authenticated"] = true
 	session.Values["uid"] = u.ID
 	session.Save(r, w)
@@ -85,7 +181,7 @@ func (s *Server) handleSingUp(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleLoginView(w http.ResponseWriter, r *http.Request) {
-	err := tmpl.ExecuteTemplate(w, "login", nil)
+	err := render(w, r, "login", nil)
 	if err != nil {
 		Error(w, r, err)
 		return
@@ -106,25 +202,158 @@ func (s *Server) handleLoginCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Refuse before checking the password, so that locked accounts don't
+	// cost a bcrypt comparison
+	ip := s.clientIP(r)
+	err = s.checkLoginThrottle(r.Context(), email, ip)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
 	user, err := s.UserService.FindUserByEmail(r.Context(), email)
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+		if err != nil {
+			err = &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Invalid password"}
+		}
+	}
+	if code := journal.ErrorCode(err); code == journal.ENOTFOUND || code == journal.ENOTAUTHORIZED {
+		if err := s.recordFailedLogin(r.Context(), email, ip); err != nil {
+			klog.Errorf("Could not record failed login for %q: %v", email, err)
+		}
+	}
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if user.Disabled {
+		Error(w, r, &journal.Error{Code: journal.EFORBIDDEN, Message: "This account is disabled"})
+		return
+	}
+
+	if user.HasTOTP() {
+		// The password is right, but the user isn't signed in until they
+		// also enter a code from their device
+		session, err := s.SessionStore.Get(r, sessionCookie)
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+		err = s.SessionStore.Renew(r, session)
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+		session.Values["totp_uid"] = user.ID
+		session.Values["totp_at"] = time.Now().Unix()
+		session.Save(r, w)
+
+		http.Redirect(w, r, "/login/totp", http.StatusSeeOther)
+		return
+	}
+
+	s.completeLogin(w, r, user)
+}
+
+// pendingTOTPUser returns the user who entered the right password, but has
+// yet to provide their second factor.
+func (s *Server) pendingTOTPUser(r *http.Request) (*journal.User, error) {
+	session, _ := s.SessionStore.Get(r, sessionCookie)
+	id, ok := session.Values["totp_uid"].(int)
+	if !ok || id <= 0 {
+		return nil, &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Please sign in first"}
+	}
+
+	at, ok := session.Values["totp_at"].(int64)
+	if !ok || time.Since(time.Unix(at, 0)) > totpLoginTimeout {
+		return nil, &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Sign in expired, please sign in again"}
+	}
+
+	return s.UserService.FindUserByID(r.Context(), id)
+}
+
+func (s *Server) handleLoginTOTPView(w http.ResponseWriter, r *http.Request) {
+	_, err := s.pendingTOTPUser(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	err = render(w, r, "logintotp", nil)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleLoginTOTP(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
 	if err != nil {
 		Error(w, r, err)
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	user, err := s.pendingTOTPUser(r)
 	if err != nil {
-		Error(w, r, &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Invalid password"})
+		Error(w, r, err)
 		return
 	}
 
-	session, err := store.Get(r, sessionCookie)
+	ip := s.clientIP(r)
+	err = s.checkLoginThrottle(r.Context(), user.Email, ip)
 	if err != nil {
 		Error(w, r, err)
 		return
 	}
 
-	session.Options.MaxAge = 60 * 60 * 24 // 24 hours
+	// Either a code from the user's device, which is refused if it was
+	// already used, or one of their recovery codes
+	code := r.Form.Get("code")
+	if counter, ok := validateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		err = s.UserService.ConsumeTOTPCounter(r.Context(), user.ID, counter)
+	} else {
+		err = s.UserService.ConsumeRecoveryCode(r.Context(), user.ID, hashRecoveryCode(code))
+	}
+	if c := journal.ErrorCode(err); c == journal.ENOTFOUND || c == journal.ENOTAUTHORIZED {
+		if err := s.recordFailedLogin(r.Context(), user.Email, ip); err != nil {
+			klog.Errorf("Could not record failed login for %q: %v", user.Email, err)
+		}
+		Error(w, r, &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Invalid code"})
+		return
+	} else if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	s.completeLogin(w, r, user)
+}
+
+// completeLogin signs the user in, once they passed every authentication
+// step.
+func (s *Server) completeLogin(w http.ResponseWriter, r *http.Request, user *journal.User) {
+	// A successful login resets the account's failures, but not the IP's
+	err := s.FailedLoginService.DeleteFailedLogins(r.Context(), normalizeEmail(user.Email))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	session, err := s.SessionStore.Get(r, sessionCookie)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = s.SessionStore.Renew(r, session)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+	delete(session.Values, "totp_uid")
+	delete(session.Values, "totp_at")
+	session.Options.MaxAge = sessionMaxAge
 	session.Values["authenticated"] = true
 	session.Values["uid"] = user.ID
 	session.Save(r, w)
@@ -138,7 +367,7 @@ func (s *Server) handleLoginCreate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
-	session, err := store.Get(r, sessionCookie)
+	session, err := s.SessionStore.Get(r, sessionCookie)
 	if err != nil {
 		Error(w, r, err)
 		return
@@ -151,3 +380,124 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusFound)
 
 }
+
+// hashPassword salts and hashes the password using the bcrypt algorithm.
+func hashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 8)
+	if err != nil {
+		return "", &journal.Error{Code: journal.EINTERNAL, Message: "Failed to process user"}
+	}
+	return string(hashedPassword), nil
+}
+
+// checkPassword returns an error unless the form has the user's current
+// password. It guards the settings that would weaken the account.
+func checkPassword(r *http.Request, user *journal.User) error {
+	err := r.ParseForm()
+	if err != nil {
+		return &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"}
+	}
+
+	return comparePassword(user, r.Form.Get("password"))
+}
+
+// comparePassword returns an error unless password is the user's current
+// password.
+func comparePassword(user *journal.User, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	if err != nil {
+		return &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Invalid password"}
+	}
+	return nil
+}
+
+// generateToken returns a new random token, used for API keys and
+// invitations. Only its hash is stored, so the token can't be shown again once
+// the response is sent.
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", &journal.Error{Code: journal.EINTERNAL, Message: "Failed to generate token"}
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken hashes a token for storage. Tokens are random and long enough
+// that a fast hash is fine, which also lets us look them up by hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// handleRole only lets through users with at least the given role. It's meant
+// to be used after handleAuth or handleAPIAuth.
+func (s *Server) handleRole(role string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := journal.UserFromContext(r.Context())
+			if user != nil && user.HasRole(role) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			err := &journal.Error{Code: journal.EFORBIDDEN, Message: fmt.Sprintf("This action requires the %s role", role)}
+			if isAPIRequest(r) {
+				ErrorJSON(w, r, err)
+			} else {
+				Error(w, r, err)
+			}
+		})
+	}
+}
+
+// canEdit reports whether the request comes from a user allowed to edit
+// content.
+func canEdit(r *http.Request) bool {
+	user := journal.UserFromContext(r.Context())
+	return user != nil && user.HasRole(journal.RoleEditor)
+}
+
+// handleAPIKey authenticates requests carrying an "Authorization: Bearer"
+// header with the user owning that API key. It must run before handleCSRF,
+// which only exempts requests marked here.
+func (s *Server) handleAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		apiKey := strings.TrimPrefix(header, "Bearer ")
+		if apiKey == header || apiKey == "" {
+			ErrorJSON(w, r, &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Invalid authorization header"})
+			return
+		}
+
+		user, err := s.UserService.FindUserByAPIKey(r.Context(), hashToken(apiKey))
+		if journal.ErrorCode(err) == journal.ENOTFOUND {
+			ErrorJSON(w, r, &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Invalid API key"})
+			return
+		} else if err != nil {
+			ErrorJSON(w, r, err)
+			return
+		}
+
+		if user.Disabled {
+			ErrorJSON(w, r, &journal.Error{Code: journal.EFORBIDDEN, Message: "This account is disabled"})
+			return
+		}
+
+		ctx := journal.NewContextWithUser(r.Context(), user)
+		r = r.WithContext(context.WithValue(ctx, apiKeyContextKey, true))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticatedByAPIKey reports whether handleAPIKey authenticated the
+// request.
+func authenticatedByAPIKey(ctx context.Context) bool {
+	ok, _ := ctx.Value(apiKeyContextKey).(bool)
+	return ok
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	journal "github.com/bertinatto/journal3"
+	"github.com/gorilla/sessions"
+)
+
+const (
+	csrfSessionKey = "csrf"
+	csrfFormField  = "csrf_token"
+	csrfHeader     = "X-CSRF-Token"
+)
+
+type contextKey int
+
+const (
+	csrfContextKey = contextKey(iota + 1)
+	menuContextKey
+	apiKeyContextKey
+)
+
+// csrfState holds the CSRF token of the current session. The token is only
+// generated when a template asks for it, so visitors who never see a form
+// don't get a stored session.
+type csrfState struct {
+	session *sessions.Session
+	dirty   bool
+}
+
+func (c *csrfState) token() (string, error) {
+	if token, ok := c.session.Values[csrfSessionKey].(string); ok && token != "" {
+		return token, nil
+	}
+
+	token, err := GenerateSessionSecret()
+	if err != nil {
+		return "", err
+	}
+	c.session.Values[csrfSessionKey] = token
+	c.dirty = true
+	return token, nil
+}
+
+func csrfFromContext(ctx context.Context) *csrfState {
+	state, _ := ctx.Value(csrfContextKey).(*csrfState)
+	return state
+}
+
+// handleCSRF rejects state-changing requests that don't carry the CSRF token
+// of the session, either as a form field or a header. Requests that
+// handleAPIKey authenticated don't rely on cookies and are let through.
+func (s *Server) handleCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, _ := s.SessionStore.Get(r, sessionCookie)
+		state := &csrfState{session: session}
+		r = r.WithContext(context.WithValue(r.Context(), csrfContextKey, state))
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if authenticatedByAPIKey(r.Context()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		expected, _ := session.Values[csrfSessionKey].(string)
+		actual := r.Header.Get(csrfHeader)
+		if actual == "" {
+			actual = r.PostFormValue(csrfFormField)
+		}
+
+		if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) != 1 {
+			err := &journal.Error{Code: journal.EFORBIDDEN, Message: "Invalid CSRF token, please reload the page and try again"}
+			if isAPIRequest(r) {
+				ErrorJSON(w, r, err)
+			} else {
+				Error(w, r, err)
+			}
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CSRFToken returns the CSRF token of the session, generating one if needed.
+// Without a CSRF state it returns nothing.
+func (d *templateData) CSRFToken() (string, error) {
+	if d.csrf == nil {
+		return "", nil
+	}
+	return d.csrf.token()
+}
+
+// CSRFField returns a hidden form input carrying the CSRF token.
+func (d *templateData) CSRFField() (template.HTML, error) {
+	token, err := d.CSRFToken()
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		csrfFormField, template.HTMLEscapeString(token))), nil
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+func TestCSRF(t *testing.T) {
+	s, _ := newTestServer(t)
+	createUser(t, s, "Ann", "ann@example.com", "secret1", journal.RoleEditor)
+
+	token, cookies := fetchCSRFToken(t, s, "/login", nil)
+	_, otherCookies := fetchCSRFToken(t, s, "/login", nil)
+
+	for _, tc := range []struct {
+		name    string
+		token   string
+		cookies []*http.Cookie
+		status  int
+	}{
+		{"missing token", "", cookies, http.StatusForbidden},
+		{"wrong token", token + "x", cookies, http.StatusForbidden},
+		{"token of another session", token, otherCookies, http.StatusForbidden},
+		{"no session", token, nil, http.StatusForbidden},
+		{"valid token", token, cookies, http.StatusSeeOther},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			form := url.Values{"email": {"ann@example.com"}, "password": {"secret1"}}
+			if tc.token != "" {
+				form.Set(csrfFormField, tc.token)
+			}
+			resp, body := serve(t, s, formRequest("POST", "/login", form, tc.cookies))
+			if resp.StatusCode != tc.status {
+				t.Errorf("got status %d, expected %d: %s", resp.StatusCode, tc.status, body)
+			}
+		})
+	}
+}
+
+func TestCSRFAPI(t *testing.T) {
+	s, _ := newTestServer(t)
+	createUser(t, s, "Ann", "ann@example.com", "secret1", journal.RoleEditor)
+
+	var c apiClient
+	c.csrf, c.cookies = fetchCSRFToken(t, s, "/settings", login(t, s, "ann@example.com", "secret1"))
+	body := `{"permalink":"new","title":"New","content":"Content","status":"published"}`
+
+	// Session cookies are sent by the browser, so the API checks the token too
+	resp, _ := serve(t, s, apiRequest("POST", "/api/v1/posts", body, apiClient{cookies: c.cookies}))
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("got status %d without a token, expected %d", resp.StatusCode, http.StatusForbidden)
+	}
+
+	resp, _ = serve(t, s, apiRequest("POST", "/api/v1/posts", body, c))
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("got status %d with the token header, expected %d", resp.StatusCode, http.StatusCreated)
+	}
+}
+
+func TestCSRFAuthorizationHeader(t *testing.T) {
+	s, _ := newTestServer(t)
+	createUser(t, s, "Ann", "ann@example.com", "secret1", journal.RoleEditor)
+	cookies := login(t, s, "ann@example.com", "secret1")
+
+	// Only a key accepted by handleAPIKey skips the token check, not any header
+	form := url.Values{"email": {"ann@example.com"}, "password": {"secret1"}}
+	r := formRequest("POST", "/login", form, cookies)
+	r.Header.Set("Authorization", "Bearer whatever")
+	resp, body := serve(t, s, r)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("got status %d on a page, expected %d: %s", resp.StatusCode, http.StatusForbidden, body)
+	}
+
+	r = apiRequest("POST", "/api/v1/posts", `{"permalink":"new","title":"New","content":"Content","status":"published"}`, apiClient{cookies: cookies})
+	r.Header.Set("Authorization", "Bearer whatever")
+	resp, body = serve(t, s, r)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got status %d on the API, expected %d: %s", resp.StatusCode, http.StatusUnauthorized, body)
+	}
+}
@@ -0,0 +1,139 @@
+package http
+
+import "strings"
+
+const (
+	diffEqual  = "equal"
+	diffInsert = "insert"
+	diffDelete = "delete"
+)
+
+// maxDiffEdits bounds the work done comparing two versions. Past it, the
+// differing lines are shown as replaced as a whole.
+const maxDiffEdits = 1000
+
+type diffLine struct {
+	Kind string
+	Text string
+}
+
+// diffLines computes a line-level diff from a to b.
+func diffLines(a, b string) []diffLine {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// Lines shared at both ends don't need to go through the diff
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]diffLine, 0, len(x)+len(y))
+	for _, text := range x[:prefix] {
+		lines = append(lines, diffLine{Kind: diffEqual, Text: text})
+	}
+	lines = append(lines, myersDiff(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	for _, text := range x[len(x)-suffix:] {
+		lines = append(lines, diffLine{Kind: diffEqual, Text: text})
+	}
+
+	return lines
+}
+
+// myersDiff finds the shortest edit script from x to y with Myers' algorithm.
+// It takes O((N+M)D) time and O(D²) space for D edits, giving up once D
+// goes over maxDiffEdits.
+func myersDiff(x, y []string) []diffLine {
+	n, m := len(x), len(y)
+	offset := n + m + 1
+
+	// v[offset+k] holds the furthest index reached in x on diagonal k, and
+	// trace[d] the diagonals -d..d of v before round d, to walk back from.
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return replaceLines(x, y)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				i = v[offset+k+1]
+			} else {
+				i = v[offset+k-1] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			v[offset+k] = i
+
+			if i >= n && j >= m {
+				return backtrackDiff(x, y, trace)
+			}
+		}
+	}
+
+	return nil
+}
+
+// backtrackDiff walks the rounds of myersDiff back from the end of x and y
+// to build the edit script.
+func backtrackDiff(x, y []string, trace [][]int) []diffLine {
+	var lines []diffLine
+	i, j := len(x), len(y)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := i - j
+
+		prevK := k - 1
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		}
+		prevI := 0
+		if d > 0 {
+			prevI = v[d+prevK]
+		}
+		prevJ := prevI - prevK
+
+		for i > prevI && j > prevJ {
+			i--
+			j--
+			lines = append(lines, diffLine{Kind: diffEqual, Text: x[i]})
+		}
+		if d == 0 {
+			break
+		}
+		if i == prevI {
+			j--
+			lines = append(lines, diffLine{Kind: diffInsert, Text: y[j]})
+		} else {
+			i--
+			lines = append(lines, diffLine{Kind: diffDelete, Text: x[i]})
+		}
+	}
+
+	for l, r := 0, len(lines)-1; l < r; l, r = l+1, r-1 {
+		lines[l], lines[r] = lines[r], lines[l]
+	}
+	return lines
+}
+
+// replaceLines shows every line of x as deleted and every line of y as
+// inserted.
+func replaceLines(x, y []string) []diffLine {
+	lines := make([]diffLine, 0, len(x)+len(y))
+	for _, text := range x {
+		lines = append(lines, diffLine{Kind: diffDelete, Text: text})
+	}
+	for _, text := range y {
+		lines = append(lines, diffLine{Kind: diffInsert, Text: text})
+	}
+	return lines
+}
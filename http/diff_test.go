@@ -0,0 +1,66 @@
+package http
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		a, b     string
+		expected []diffLine
+	}{
+		{"equal", "a\nb", "a\nb", []diffLine{{diffEqual, "a"}, {diffEqual, "b"}}},
+		{"insert", "a\nc", "a\nb\nc", []diffLine{{diffEqual, "a"}, {diffInsert, "b"}, {diffEqual, "c"}}},
+		{"delete", "a\nb\nc", "a\nc", []diffLine{{diffEqual, "a"}, {diffDelete, "b"}, {diffEqual, "c"}}},
+		{"change", "a\nb\nc", "a\nx\nc", []diffLine{{diffEqual, "a"}, {diffDelete, "b"}, {diffInsert, "x"}, {diffEqual, "c"}}},
+		{"from empty", "", "a", []diffLine{{diffDelete, ""}, {diffInsert, "a"}}},
+		{"moved line", "a\nb\nc\nd", "b\nc\na\nd", []diffLine{{diffDelete, "a"}, {diffEqual, "b"}, {diffEqual, "c"}, {diffInsert, "a"}, {diffEqual, "d"}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := diffLines(tc.a, tc.b)
+			if !reflect.DeepEqual(lines, tc.expected) {
+				t.Errorf("got %v, expected %v", lines, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	// Every other line differs, so the diff needs more than maxDiffEdits edits
+	var a, b []string
+	for i := 0; i < 2*maxDiffEdits; i++ {
+		a = append(a, fmt.Sprintf("line %d", i))
+		if i%2 == 0 {
+			b = append(b, fmt.Sprintf("line %d", i))
+		} else {
+			b = append(b, fmt.Sprintf("changed %d", i))
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		a, b []string
+	}{
+		{"few edits", a, append(append([]string{"first"}, a...), "last")},
+		{"many edits", a, b},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var from, to []string
+			for _, line := range diffLines(strings.Join(tc.a, "\n"), strings.Join(tc.b, "\n")) {
+				if line.Kind != diffInsert {
+					from = append(from, line.Text)
+				}
+				if line.Kind != diffDelete {
+					to = append(to, line.Text)
+				}
+			}
+			if !reflect.DeepEqual(from, tc.a) || !reflect.DeepEqual(to, tc.b) {
+				t.Errorf("diff doesn't turn one version into the other")
+			}
+		})
+	}
+}
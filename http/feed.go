@@ -0,0 +1,255 @@
+package http
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+const (
+	feedTitle    = "Journal"
+	feedMaxPosts = 20
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Link      atomLink    `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// jsonFeed follows the JSON Feed 1.1 spec: https://www.jsonfeed.org/version/1.1/
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Authors     []jsonFeedAuthor `json:"authors"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html"`
+	ContentText   string   `json:"content_text"`
+	DatePublished string   `json:"date_published"`
+	DateModified  string   `json:"date_modified"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+// findFeedPosts returns the latest published posts and the time the most
+// recent one was updated.
+func (s *Server) findFeedPosts(r *http.Request) ([]*journal.Post, time.Time, error) {
+	status := journal.PostStatusPublished
+	posts, _, err := s.JournalService.FindPosts(r.Context(), &journal.PostFilter{
+		Status: &status,
+		Limit:  feedMaxPosts,
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// An empty feed is considered updated now
+	updated := time.Time{}
+	if len(posts) == 0 {
+		updated = time.Now()
+	}
+	for _, post := range posts {
+		if post.UpdatedAt.After(updated) {
+			updated = post.UpdatedAt
+		}
+	}
+
+	return posts, updated, nil
+}
+
+func (s *Server) handleFeedAtom(w http.ResponseWriter, r *http.Request) {
+	posts, updated, err := s.findFeedPosts(r)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	baseURL := s.BaseURL(r)
+	feed := &atomFeed{
+		ID:      baseURL + "/",
+		Title:   feedTitle,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: s.feedAuthor()},
+		Links: []atomLink{
+			{Href: baseURL + "/"},
+			{Href: baseURL + "/feed.atom", Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+
+	for _, post := range posts {
+		link := baseURL + "/post/" + post.Permalink
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        link,
+			Title:     post.Title,
+			Published: post.Date().UTC().Format(time.RFC3339),
+			Updated:   post.UpdatedAt.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: link},
+			Content:   atomContent{Type: "html", Body: renderMarkdown(post.Content)},
+		})
+	}
+
+	writeXML(w, r, "application/atom+xml; charset=utf-8", feed)
+}
+
+func (s *Server) handleFeedRSS(w http.ResponseWriter, r *http.Request) {
+	posts, updated, err := s.findFeedPosts(r)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	baseURL := s.BaseURL(r)
+	feed := &rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       feedTitle,
+			Link:        baseURL + "/",
+			Description: feedTitle,
+		},
+	}
+	feed.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+
+	for _, post := range posts {
+		link := baseURL + "/post/" + post.Permalink
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       post.Title,
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: true, Value: link},
+			PubDate:     post.Date().UTC().Format(time.RFC1123Z),
+			Description: renderMarkdown(post.Content),
+		})
+	}
+
+	writeXML(w, r, "application/rss+xml; charset=utf-8", feed)
+}
+
+func (s *Server) handleFeedJSON(w http.ResponseWriter, r *http.Request) {
+	posts, _, err := s.findFeedPosts(r)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	baseURL := s.BaseURL(r)
+	feed := &jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feedTitle,
+		HomePageURL: baseURL + "/",
+		FeedURL:     baseURL + "/feed.json",
+		Authors:     []jsonFeedAuthor{{Name: s.feedAuthor()}},
+		Items:       []jsonFeedItem{},
+	}
+
+	for _, post := range posts {
+		link := baseURL + "/post/" + post.Permalink
+		feed.Items = append(feed.Items, jsonFeedItem{
+			ID:            link,
+			URL:           link,
+			Title:         post.Title,
+			ContentHTML:   renderMarkdown(post.Content),
+			ContentText:   post.Content,
+			DatePublished: post.Date().UTC().Format(time.RFC3339),
+			DateModified:  post.UpdatedAt.UTC().Format(time.RFC3339),
+			Tags:          post.Tags,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(feed)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func writeXML(w http.ResponseWriter, r *http.Request, contentType string, v interface{}) {
+	buf, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write([]byte(xml.Header))
+	w.Write(buf)
+}
+
+// feedAuthor returns the name credited for the posts in feeds, which
+// defaults to the feed title.
+func (s *Server) feedAuthor() string {
+	if s.Author != "" {
+		return s.Author
+	}
+	return feedTitle
+}
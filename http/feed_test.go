@@ -0,0 +1,145 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+// createFeedPosts creates one published post and one draft.
+func createFeedPosts(t *testing.T, s *Server) {
+	t.Helper()
+
+	for _, post := range []*journal.Post{
+		{Permalink: "hello", Title: "Hello", Content: "Hello *world*", Status: journal.PostStatusPublished, Tags: []string{"greeting"}},
+		{Permalink: "draft", Title: "Draft", Content: "Not yet", Status: journal.PostStatusDraft},
+	} {
+		err := s.JournalService.CreatePost(context.Background(), post)
+		if err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+	}
+}
+
+func TestFeedAtom(t *testing.T) {
+	s, _ := newTestServer(t)
+	createFeedPosts(t, s)
+
+	resp, body := serve(t, s, httptest.NewRequest("GET", "/feed.atom", nil))
+	if ct := resp.Header.Get("Content-Type"); ct != "application/atom+xml; charset=utf-8" {
+		t.Errorf("got content type %q", ct)
+	}
+
+	var feed atomFeed
+	err := xml.Unmarshal([]byte(body), &feed)
+	if err != nil {
+		t.Fatalf("failed to parse feed: %v", err)
+	}
+	if len(feed.Entries) != 1 {
+		t.Fatalf("got %d entries, expected only the published post", len(feed.Entries))
+	}
+	entry := feed.Entries[0]
+	if expected := "http://example.com/post/hello"; entry.ID != expected || entry.Link.Href != expected {
+		t.Errorf("got entry id %q and link %q, expected %q", entry.ID, entry.Link.Href, expected)
+	}
+	if expected := "<p>Hello <em>world</em></p>\n"; entry.Content.Body != expected {
+		t.Errorf("got content %q, expected %q", entry.Content.Body, expected)
+	}
+}
+
+func TestFeedRSS(t *testing.T) {
+	s, _ := newTestServer(t)
+	createFeedPosts(t, s)
+
+	resp, body := serve(t, s, httptest.NewRequest("GET", "/feed.rss", nil))
+	if ct := resp.Header.Get("Content-Type"); ct != "application/rss+xml; charset=utf-8" {
+		t.Errorf("got content type %q", ct)
+	}
+
+	var feed rssFeed
+	err := xml.Unmarshal([]byte(body), &feed)
+	if err != nil {
+		t.Fatalf("failed to parse feed: %v", err)
+	}
+	if len(feed.Channel.Items) != 1 {
+		t.Fatalf("got %d items, expected only the published post", len(feed.Channel.Items))
+	}
+	if item := feed.Channel.Items[0]; item.Title != "Hello" || item.GUID.Value != "http://example.com/post/hello" {
+		t.Errorf("got item %q with guid %q", item.Title, item.GUID.Value)
+	}
+}
+
+func TestFeedJSON(t *testing.T) {
+	s, _ := newTestServer(t)
+	createFeedPosts(t, s)
+
+	resp, body := serve(t, s, httptest.NewRequest("GET", "/feed.json", nil))
+	if ct := resp.Header.Get("Content-Type"); ct != "application/feed+json; charset=utf-8" {
+		t.Errorf("got content type %q", ct)
+	}
+
+	var feed jsonFeed
+	err := json.Unmarshal([]byte(body), &feed)
+	if err != nil {
+		t.Fatalf("failed to parse feed: %v", err)
+	}
+	if feed.Version != "https://jsonfeed.org/version/1.1" || feed.FeedURL != "http://example.com/feed.json" {
+		t.Errorf("got version %q and feed url %q", feed.Version, feed.FeedURL)
+	}
+	if len(feed.Items) != 1 {
+		t.Fatalf("got %d items, expected only the published post", len(feed.Items))
+	}
+	item := feed.Items[0]
+	if item.URL != "http://example.com/post/hello" || item.ContentText != "Hello *world*" {
+		t.Errorf("got item url %q and text %q", item.URL, item.ContentText)
+	}
+	if expected := []string{"greeting"}; !reflect.DeepEqual(item.Tags, expected) {
+		t.Errorf("got tags %v, expected %v", item.Tags, expected)
+	}
+}
+
+func TestFeedPublishedDate(t *testing.T) {
+	s, db := newTestServer(t)
+	ctx := context.Background()
+
+	now := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	db.Now = func() time.Time { return now }
+
+	// The draft is started first but published after the other post
+	for _, post := range []*journal.Post{
+		{Permalink: "draft", Title: "Draft", Content: "Content", Status: journal.PostStatusDraft},
+		{Permalink: "hello", Title: "Hello", Content: "Content", Status: journal.PostStatusPublished},
+	} {
+		err := s.JournalService.CreatePost(ctx, post)
+		if err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+		now = now.Add(time.Hour)
+	}
+	published := now
+	status := journal.PostStatusPublished
+	err := s.JournalService.UpdatePost(ctx, "draft", &journal.PostUpdate{Status: &status})
+	if err != nil {
+		t.Fatalf("failed to publish post: %v", err)
+	}
+
+	_, body := serve(t, s, httptest.NewRequest("GET", "/feed.atom", nil))
+	var feed atomFeed
+	err = xml.Unmarshal([]byte(body), &feed)
+	if err != nil {
+		t.Fatalf("failed to parse feed: %v", err)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("got %d entries, expected 2", len(feed.Entries))
+	}
+	entry := feed.Entries[0]
+	if entry.Title != "Draft" || entry.Published != published.Format(time.RFC3339) {
+		t.Errorf("got first entry %q published %s, expected %q published %s", entry.Title, entry.Published, "Draft", published.Format(time.RFC3339))
+	}
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"encoding/json"
 	"net/http"
 
 	journal "github.com/bertinatto/journal3"
@@ -8,9 +9,12 @@ import (
 )
 
 var errorCodes = map[string]int{
-	journal.ENOTFOUND: http.StatusNotFound,
-	journal.EBADINPUT: http.StatusBadRequest,
-	journal.EINTERNAL: http.StatusInternalServerError,
+	journal.ENOTFOUND:      http.StatusNotFound,
+	journal.EBADINPUT:      http.StatusBadRequest,
+	journal.EINTERNAL:      http.StatusInternalServerError,
+	journal.ENOTAUTHORIZED: http.StatusUnauthorized,
+	journal.EFORBIDDEN:     http.StatusForbidden,
+	journal.ETOOMANY:       http.StatusTooManyRequests,
 }
 
 func ErrorStatusCode(code string) int {
@@ -24,9 +28,30 @@ func Error(w http.ResponseWriter, r *http.Request, err error) {
 	klog.Error(err)
 	code, message := journal.ErrorCode(err), journal.ErrorMessage(err)
 	w.WriteHeader(ErrorStatusCode(code))
-	err = tmpl.ExecuteTemplate(w, "error", &journal.Error{Message: message})
+	err = render(w, r, "error", &journal.Error{Message: message})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
+
+// ErrorJSON writes err as a JSON object, for API clients.
+func ErrorJSON(w http.ResponseWriter, r *http.Request, err error) {
+	klog.Error(err)
+	code, message := journal.ErrorCode(err), journal.ErrorMessage(err)
+	writeJSON(w, r, ErrorStatusCode(code), &errorResponse{Code: code, Message: message})
+}
+
+type errorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		klog.Errorf("Failed to encode response for %s: %v", r.URL.Path, err)
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+	"github.com/gorilla/mux"
+)
+
+const defaultInvitationDays = 7
+
+type invitationsData struct {
+	Invitations []*journal.Invitation
+	Now         time.Time
+
+	// NewLink is only set right after an invitation is created
+	NewLink string
+}
+
+func (s *Server) renderInvitations(w http.ResponseWriter, r *http.Request, newLink string) {
+	invitations, _, err := s.InvitationService.FindInvitations(r.Context(), &journal.InvitationFilter{})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "invitations", &invitationsData{
+		Invitations: invitations,
+		Now:         time.Now(),
+		NewLink:     newLink,
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleInvitations(w http.ResponseWriter, r *http.Request) {
+	s.renderInvitations(w, r, "")
+}
+
+func (s *Server) handleInvitationCreate(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"})
+		return
+	}
+
+	days := defaultInvitationDays
+	if v := r.Form.Get("days"); v != "" {
+		days, err = strconv.Atoi(v)
+		if err != nil || days < 1 {
+			Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid number of days: %s", v)})
+			return
+		}
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = s.InvitationService.CreateInvitation(r.Context(), &journal.Invitation{
+		TokenHash: hashToken(token),
+		CreatedBy: journal.UserIDFromContext(r.Context()),
+		ExpiresAt: time.Now().AddDate(0, 0, days),
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	s.renderInvitations(w, r, s.BaseURL(r)+"/signup?invite="+url.QueryEscape(token))
+}
+
+func (s *Server) handleInvitationDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Invalid invitation"})
+		return
+	}
+
+	err = s.InvitationService.DeleteInvitation(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/invitations", http.StatusFound)
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	journal "github.com/bertinatto/journal3"
+	"github.com/gorilla/mux"
+	"k8s.io/klog/v2"
+)
+
+type adminMenuData struct {
+	Items []*journal.MenuItem
+	Types []string
+}
+
+// handleMenu loads the header menu into the request context, where render
+// picks it up.
+func (s *Server) handleMenu(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		items, _, err := s.MenuService.FindMenuItems(r.Context(), &journal.MenuItemFilter{})
+		if err != nil {
+			// The menu isn't worth failing the request over
+			klog.Errorf("Failed to load menu: %v", err)
+		}
+		r = r.WithContext(context.WithValue(r.Context(), menuContextKey, items))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func menuFromContext(ctx context.Context) []*journal.MenuItem {
+	items, _ := ctx.Value(menuContextKey).([]*journal.MenuItem)
+	return items
+}
+
+func (s *Server) handleAdminMenu(w http.ResponseWriter, r *http.Request) {
+	items, _, err := s.MenuService.FindMenuItems(r.Context(), &journal.MenuItemFilter{})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "adminmenu", &adminMenuData{
+		Items: items,
+		Types: []string{journal.MenuItemPage, journal.MenuItemPost, journal.MenuItemTag, journal.MenuItemURL},
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleAdminMenuCreate(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"})
+		return
+	}
+
+	item := &journal.MenuItem{
+		Label:  strings.TrimSpace(r.Form.Get("label")),
+		Type:   r.Form.Get("type"),
+		Target: strings.TrimSpace(r.Form.Get("target")),
+	}
+
+	err = item.Validate()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid menu item: %v", err)})
+		return
+	}
+
+	err = s.MenuService.CreateMenuItem(r.Context(), item)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/menu", http.StatusFound)
+}
+
+// handleAdminMenuUpdate renames an item or moves it one step up or down.
+func (s *Server) handleAdminMenuUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Invalid menu item"})
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"})
+		return
+	}
+
+	items, n, err := s.MenuService.FindMenuItems(r.Context(), &journal.MenuItemFilter{ID: &id})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+	if n == 0 {
+		Error(w, r, &journal.Error{Code: journal.ENOTFOUND, Message: "Menu item not found"})
+		return
+	}
+
+	updated := &journal.MenuItemUpdate{}
+	if _, ok := r.Form["label"]; ok {
+		label := strings.TrimSpace(r.Form.Get("label"))
+		if label == "" {
+			Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Invalid menu item: label is required"})
+			return
+		}
+		updated.Label = &label
+	}
+
+	switch r.Form.Get("move") {
+	case "up":
+		position := items[0].Position - 1
+		updated.Position = &position
+	case "down":
+		position := items[0].Position + 1
+		updated.Position = &position
+	}
+
+	err = s.MenuService.UpdateMenuItem(r.Context(), id, updated)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/menu", http.StatusFound)
+}
+
+func (s *Server) handleAdminMenuDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Invalid menu item"})
+		return
+	}
+
+	err = s.MenuService.DeleteMenuItem(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/menu", http.StatusFound)
+}
@@ -2,11 +2,64 @@ package http
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	journal "github.com/bertinatto/journal3"
+	"github.com/gorilla/mux"
 )
 
+const nowsPerPage = 10
+
+// nowData is rendered by the "now" template. Latest is false when showing
+// an older snapshot, and LocalTime is only set for the latest one.
+type nowData struct {
+	Now       *journal.Now
+	Latest    bool
+	LocalTime *time.Time
+}
+
+type nowArchiveData struct {
+	Nows       []*journal.Now
+	Pagination Pagination
+}
+
+// mapPoint is a now entry placed on the map drawn by the "nowmap" template.
+type mapPoint struct {
+	X      float64
+	Y      float64
+	Now    *journal.Now
+	Latest bool
+}
+
+type nowMapData struct {
+	// Points are ordered from the oldest to the latest entry
+	Points []mapPoint
+}
+
+// projectLocation converts coordinates to the equirectangular projection of
+// the "worldmap" template, which spans 360x180 units.
+func projectLocation(latitude, longitude float64) (x, y float64) {
+	return longitude + 180, 90 - latitude
+}
+
+// parseCoordinate parses an optional latitude or longitude form value.
+func parseCoordinate(value string) (*float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid coordinate: %s", value)}
+	}
+	return &f, nil
+}
+
 func (s *Server) handleNowCreate(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
@@ -14,16 +67,47 @@ func (s *Server) handleNowCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	location := r.Form.Get("location")
+	city := strings.TrimSpace(r.Form.Get("city"))
+	country := strings.TrimSpace(r.Form.Get("country"))
+
+	// The free text location defaults to the structured one
+	location := strings.TrimSpace(r.Form.Get("location"))
+	if location == "" {
+		location = strings.Trim(city+", "+country, ", ")
+	}
+
 	content := r.Form.Get("content")
 	if location == "" || content == "" {
 		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing parameters: location and/or content"})
 		return
 	}
 
+	latitude, err := parseCoordinate(r.Form.Get("latitude"))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	longitude, err := parseCoordinate(r.Form.Get("longitude"))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
 	now := &journal.Now{
 		Content:      content,
 		FromLocation: location,
+		City:         city,
+		Country:      country,
+		Latitude:     latitude,
+		Longitude:    longitude,
+		Timezone:     strings.TrimSpace(r.Form.Get("timezone")),
+	}
+
+	err = now.Validate()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid location: %v", err)})
+		return
 	}
 
 	err = s.NowService.CreateNow(r.Context(), now)
@@ -42,7 +126,60 @@ func (s *Server) handleNowView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = tmpl.ExecuteTemplate(w, "now", now)
+	data := &nowData{Now: now, Latest: true}
+	if t, ok := now.LocalTime(time.Now()); ok {
+		data.LocalTime = &t
+	}
+
+	err = render(w, r, "now", data)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+// handleNowEntryView shows a past snapshot of the now page.
+func (s *Server) handleNowEntryView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Invalid now entry"})
+		return
+	}
+
+	now, err := s.NowService.FindNowByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	latest, err := s.NowService.FindLatestNow(r.Context())
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "now", &nowData{Now: now, Latest: now.ID == latest.ID})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleNowArchive(w http.ResponseWriter, r *http.Request) {
+	page := pageFromRequest(r)
+	nows, n, err := s.NowService.FindNows(r.Context(), &journal.NowFilter{
+		Offset: (page - 1) * nowsPerPage,
+		Limit:  nowsPerPage,
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "nowarchive", &nowArchiveData{
+		Nows:       nows,
+		Pagination: newPagination(page, nowsPerPage, n),
+	})
 	if err != nil {
 		Error(w, r, err)
 		return
@@ -64,7 +201,31 @@ func (s *Server) handleNowEdit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = tmpl.ExecuteTemplate(w, "editnow", now)
+	err = render(w, r, "editnow", now)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleNowMap(w http.ResponseWriter, r *http.Request) {
+	nows, _, err := s.NowService.FindNows(r.Context(), &journal.NowFilter{})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	data := &nowMapData{}
+	for i := len(nows) - 1; i >= 0; i-- {
+		now := nows[i]
+		if !now.HasCoordinates() {
+			continue
+		}
+		x, y := projectLocation(*now.Latitude, *now.Longitude)
+		data.Points = append(data.Points, mapPoint{X: x, Y: y, Now: now, Latest: i == 0})
+	}
+
+	err = render(w, r, "nowmap", data)
 	if err != nil {
 		Error(w, r, err)
 		return
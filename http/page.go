@@ -0,0 +1,195 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	journal "github.com/bertinatto/journal3"
+	"github.com/gorilla/mux"
+)
+
+type adminPagesData struct {
+	Pages []*journal.Page
+}
+
+// pageFromForm reads the title and content of a page from a parsed form.
+func pageFromForm(r *http.Request) (title, content string) {
+	title = strings.TrimSpace(r.Form.Get("title"))
+	content = strings.TrimSpace(strings.ReplaceAll(r.Form.Get("content"), "\r\n", "\n"))
+	return title, content
+}
+
+// handlePageCreate creates a page named after the URL or, when posted from
+// the admin pages screen, after the name field.
+func (s *Server) handlePageCreate(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"})
+		return
+	}
+
+	name, ok := mux.Vars(r)["name"]
+	if !ok {
+		name = strings.TrimSpace(r.Form.Get("name"))
+	}
+
+	title, content := pageFromForm(r)
+	if content == "" {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing parameters: content"})
+		return
+	}
+
+	page := &journal.Page{
+		Name:    name,
+		Title:   title,
+		Content: content,
+	}
+
+	err = page.Validate()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid page: %v", err)})
+		return
+	}
+
+	err = s.PageService.CreatePage(r.Context(), page)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if r.Form.Get("in_menu") != "" {
+		err = s.MenuService.CreateMenuItem(r.Context(), &journal.MenuItem{
+			Label:  page.Title,
+			Type:   journal.MenuItemPage,
+			Target: page.Name,
+		})
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/p/%s", page.Name), http.StatusFound)
+}
+
+func (s *Server) handlePageView(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var e *journal.Error
+	page, err := s.PageService.FindPageByName(r.Context(), name)
+	if errors.As(err, &e) {
+		if e.Code == journal.ENOTFOUND {
+			if !canEdit(r) {
+				s.handleNotFound(w, r)
+				return
+			}
+			err = render(w, r, "newpage", name)
+			if err != nil {
+				Error(w, r, err)
+				return
+			}
+			return
+		}
+	}
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "page", page)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handlePageEdit(w http.ResponseWriter, r *http.Request) {
+	page, err := s.PageService.FindPageByName(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "editpage", page)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handlePageUpdate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	err := r.ParseForm()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"})
+		return
+	}
+
+	title, content := pageFromForm(r)
+	if title == "" || content == "" {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing parameters: title and/or content"})
+		return
+	}
+
+	updatedPage := &journal.PageUpdate{
+		Title:   &title,
+		Content: &content,
+	}
+
+	err = s.PageService.UpdatePage(r.Context(), name, updatedPage)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/p/%s", name), http.StatusFound)
+}
+
+func (s *Server) handlePageDeleteConfirm(w http.ResponseWriter, r *http.Request) {
+	page, err := s.PageService.FindPageByName(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "deletepage", page)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handlePageDelete(w http.ResponseWriter, r *http.Request) {
+	err := s.PageService.DeletePage(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// handlePageRedirect sends the old fixed page URLs, like /about, to their
+// generic location.
+func handlePageRedirect(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, fmt.Sprintf("/p/%s", name), http.StatusMovedPermanently)
+	}
+}
+
+func (s *Server) handleAdminPages(w http.ResponseWriter, r *http.Request) {
+	pages, _, err := s.PageService.FindPages(r.Context(), &journal.PageFilter{})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "adminpages", &adminPagesData{Pages: pages})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	journal "github.com/bertinatto/journal3"
+	"github.com/gorilla/mux"
+)
+
+type pageRevisionsData struct {
+	Page      *journal.Page
+	Revisions []*journal.PageRevision
+}
+
+type pageDiffData struct {
+	Page *journal.Page
+	From *journal.PageRevision
+	To   *journal.PageRevision
+	revisionDiff
+}
+
+func (s *Server) handlePageRevisions(w http.ResponseWriter, r *http.Request) {
+	page, err := s.PageService.FindPageByName(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	revisions, _, err := s.PageService.FindPageRevisions(r.Context(), &journal.PageRevisionFilter{PageID: &page.ID})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "pagerevisions", &pageRevisionsData{Page: page, Revisions: revisions})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handlePageDiff(w http.ResponseWriter, r *http.Request) {
+	page, err := s.PageService.FindPageByName(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	from, err := s.findPageRevision(r, page, r.URL.Query().Get("from"))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	to, err := s.findPageRevision(r, page, r.URL.Query().Get("to"))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "pagediff", &pageDiffData{
+		Page:         page,
+		From:         from,
+		To:           to,
+		revisionDiff: diffRevisions(from.Title, from.Content, to.Title, to.Content),
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handlePageRevisionRestore(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	page, err := s.PageService.FindPageByName(r.Context(), name)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	revision, err := s.findPageRevision(r, page, mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = s.PageService.UpdatePage(r.Context(), name, &journal.PageUpdate{
+		Title:   &revision.Title,
+		Content: &revision.Content,
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/p/%s", name), http.StatusFound)
+}
+
+// findPageRevision looks up a revision of page by its ID, as parsed by
+// parseRevisionID.
+func (s *Server) findPageRevision(r *http.Request, page *journal.Page, id string) (*journal.PageRevision, error) {
+	revisionID, err := parseRevisionID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if revisionID == 0 {
+		return &journal.PageRevision{
+			PageID:    page.ID,
+			Title:     page.Title,
+			Content:   page.Content,
+			CreatedAt: page.UpdatedAt,
+		}, nil
+	}
+
+	revision, err := s.PageService.FindPageRevisionByID(r.Context(), revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't leak revisions from other pages
+	if revision.PageID != page.ID {
+		return nil, &journal.Error{Code: journal.ENOTFOUND, Message: "Revision not found"}
+	}
+
+	return revision, nil
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const postsPerPage = 10
+
+// Pagination holds the page numbers rendered by the "pagination" template.
+// PrevPage and NextPage are zero when there is no such page.
+type Pagination struct {
+	Page     int
+	PrevPage int
+	NextPage int
+}
+
+// pageFromRequest returns the 1-based page number from the "page" query
+// parameter, defaulting to the first page.
+func pageFromRequest(r *http.Request) int {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		return 1
+	}
+	return page
+}
+
+func newPagination(page, perPage, total int) Pagination {
+	p := Pagination{Page: page}
+	if page > 1 {
+		p.PrevPage = page - 1
+	}
+	if page*perPage < total {
+		p.NextPage = page + 1
+	}
+	return p
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+func TestIndexPagination(t *testing.T) {
+	s, _ := newTestServer(t)
+	ctx := context.Background()
+
+	for i := 1; i <= postsPerPage+2; i++ {
+		err := s.JournalService.CreatePost(ctx, &journal.Post{
+			Permalink: fmt.Sprintf("post-%02d", i),
+			Title:     fmt.Sprintf("Post %d", i),
+			Content:   "Content",
+			Status:    journal.PostStatusPublished,
+		})
+		if err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		url     string
+		present []string
+		absent  []string
+	}{
+		{
+			url:     "/",
+			present: []string{`/post/post-12"`, `/post/post-03"`, `href="?page=2"`},
+			absent:  []string{`/post/post-02"`, `href="?page=0"`, "Newer"},
+		},
+		{
+			url:     "/?page=2",
+			present: []string{`/post/post-02"`, `/post/post-01"`, `href="?page=1"`},
+			absent:  []string{`/post/post-03"`, "Older"},
+		},
+		{
+			url:     "/?page=bogus",
+			present: []string{`/post/post-12"`, `href="?page=2"`},
+		},
+	} {
+		resp, body := serve(t, s, httptest.NewRequest("GET", tc.url, nil))
+		if resp.StatusCode != 200 {
+			t.Fatalf("got status %d for %s, expected 200", resp.StatusCode, tc.url)
+		}
+		for _, s := range tc.present {
+			if !strings.Contains(body, s) {
+				t.Errorf("%s: expected %s in the body", tc.url, s)
+			}
+		}
+		for _, s := range tc.absent {
+			if strings.Contains(body, s) {
+				t.Errorf("%s: unexpected %s in the body", tc.url, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+	"k8s.io/klog/v2"
+)
+
+const (
+	passwordResetExpiry = time.Hour
+
+	// Users can't ask for more than one email in this interval
+	passwordResetInterval = 5 * time.Minute
+)
+
+// errNoConfiguredURL is returned rather than emailing links built from the
+// request's Host header, which would let anyone pick where they point.
+var errNoConfiguredURL = &journal.Error{
+	Code:    journal.EINTERNAL,
+	Message: "Emails with links can't be sent until the site's URL is configured with -base-url or -domain",
+}
+
+type passwordResetData struct {
+	Token string
+	Sent  bool
+}
+
+func (s *Server) handlePasswordForgotView(w http.ResponseWriter, r *http.Request) {
+	err := render(w, r, "passwordforgot", &passwordResetData{})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handlePasswordForgot(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"})
+		return
+	}
+
+	email := r.Form.Get("email")
+	if email == "" {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing parameters: email is required"})
+		return
+	}
+
+	// Don't reveal whether the account exists, or whether sending failed,
+	// the page is the same either way
+	user, err := s.UserService.FindUserByEmail(r.Context(), email)
+	if err == nil {
+		err = s.sendPasswordResetOnce(r, user)
+	}
+	if err != nil && journal.ErrorCode(err) != journal.ENOTFOUND {
+		klog.Errorf("Could not send password reset for %q: %v", email, err)
+	}
+
+	err = render(w, r, "passwordforgot", &passwordResetData{Sent: true})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+// sendPasswordResetOnce sends a password reset, unless one was sent to the
+// user recently.
+func (s *Server) sendPasswordResetOnce(r *http.Request, user *journal.User) error {
+	resets, n, err := s.PasswordResetService.FindPasswordResets(r.Context(), &journal.PasswordResetFilter{UserID: &user.ID, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if n > 0 && time.Since(resets[0].CreatedAt) < passwordResetInterval {
+		klog.Infof("Not sending another password reset to user %d so soon", user.ID)
+		return nil
+	}
+
+	return s.sendPasswordReset(r, user)
+}
+
+// sendPasswordReset emails the user a link to choose a new password.
+func (s *Server) sendPasswordReset(r *http.Request, user *journal.User) error {
+	baseURL := s.configuredURL()
+	if baseURL == "" {
+		return errNoConfiguredURL
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.PasswordResetService.CreatePasswordReset(r.Context(), &journal.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetExpiry),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := baseURL + "/password/reset?token=" + url.QueryEscape(token)
+	return s.Mailer.SendMail(r.Context(), &journal.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your account. If it was you, follow this link to choose a new password:\n\n"+
+			"%s\n\n"+
+			"The link expires in %.0f minutes. If you didn't ask for it, you can ignore this email.\n",
+			user.Name, link, passwordResetExpiry.Minutes()),
+	})
+}
+
+func (s *Server) handlePasswordResetView(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	// Tell the user upfront rather than after they typed a new password
+	hash := hashToken(token)
+	resets, n, err := s.PasswordResetService.FindPasswordResets(r.Context(), &journal.PasswordResetFilter{TokenHash: &hash})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+	if n == 0 || !resets[0].IsValid(time.Now()) {
+		Error(w, r, &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Invalid or expired password reset"})
+		return
+	}
+
+	err = render(w, r, "passwordreset", &passwordResetData{Token: token})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handlePasswordReset(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"})
+		return
+	}
+
+	token := r.Form.Get("token")
+	password := r.Form.Get("password")
+	if password != r.Form.Get("confirm") {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Passwords don't match"})
+		return
+	}
+
+	err = journal.ValidatePassword(password)
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid password: %v", err)})
+		return
+	}
+
+	hashed, err := hashPassword(password)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	reset, err := s.PasswordResetService.ConsumePasswordReset(r.Context(), hashToken(token))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	user, err := s.UserService.FindUserByID(r.Context(), reset.UserID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = s.UserService.UpdateUser(r.Context(), user.ID, &journal.UserUpdate{Password: &hashed})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Whoever knew the old password must not stay signed in, and the owner
+	// shouldn't stay locked out
+	err = s.deleteUserSessions(r.Context(), user.ID, "")
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = s.FailedLoginService.DeleteFailedLogins(r.Context(), normalizeEmail(user.Email))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
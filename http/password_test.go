@@ -0,0 +1,50 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+func TestPasswordForgot(t *testing.T) {
+	s, _ := newTestServer(t)
+	mailer := s.Mailer.(*testMailer)
+	createUser(t, s, "Ann", "ann@example.com", "secret1", journal.RoleReader)
+	createUser(t, s, "Bob", "bob@example.com", "secret2", journal.RoleReader)
+	createUser(t, s, "Carol", "carol@example.com", "secret3", journal.RoleReader)
+
+	token, cookies := fetchCSRFToken(t, s, "/password/forgot", nil)
+	forgot := func(email string) string {
+		t.Helper()
+		form := url.Values{"email": {email}, csrfFormField: {token}}
+		resp, body := serve(t, s, formRequest("POST", "/password/forgot", form, cookies))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d for %s, expected %d: %s", resp.StatusCode, email, http.StatusOK, body)
+		}
+		return body
+	}
+
+	// Without a configured URL no link can be sent
+	noURL := forgot("ann@example.com")
+
+	s.PublicURL = "https://journal.example.com"
+	sent := forgot("bob@example.com")
+	if len(mailer.messages) != 1 || mailer.messages[0].To != "bob@example.com" {
+		t.Fatalf("got %d messages, expected one to bob@example.com", len(mailer.messages))
+	}
+
+	unknown := forgot("nobody@example.com")
+
+	mailer.err = errors.New("connection refused")
+	failed := forgot("carol@example.com")
+
+	// None of these may tell whether the account exists
+	for name, body := range map[string]string{"no URL": noURL, "unknown account": unknown, "failed email": failed} {
+		if body != sent {
+			t.Errorf("got a different page for %s than for a sent email", name)
+		}
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	journal "github.com/bertinatto/journal3"
 	"github.com/gorilla/mux"
 )
 
+// publishAtLayout matches the value of an HTML datetime-local input.
+const publishAtLayout = "2006-01-02T15:04"
+
 func (s *Server) handlePostEdit(w http.ResponseWriter, r *http.Request) {
 	permalink, ok := mux.Vars(r)["permalink"]
 	if !ok {
@@ -23,7 +27,7 @@ func (s *Server) handlePostEdit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = tmpl.ExecuteTemplate(w, "editpost", post)
+	err = render(w, r, "editpost", post)
 	if err != nil {
 		Error(w, r, err)
 		return
@@ -40,6 +44,7 @@ func (s *Server) handlePostUpdate(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
 		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"})
+		return
 	}
 
 	title := strings.TrimSpace(r.Form.Get("title"))
@@ -49,10 +54,27 @@ func (s *Server) handlePostUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tags := parseTags(r.Form.Get("tags"))
 	updatedPost := &journal.PostUpdate{
 		Title:   &title,
 		Content: &content,
+		Tags:    &tags,
+	}
+
+	if status := r.Form.Get("status"); status != "" {
+		if !journal.IsValidPostStatus(status) {
+			Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid status: %s", status)})
+			return
+		}
+		updatedPost.Status = &status
+	}
+
+	publishAt, err := parsePublishAt(r.Form.Get("publish_at"))
+	if err != nil {
+		Error(w, r, err)
+		return
 	}
+	updatedPost.PublishAt = &publishAt
 
 	err = s.JournalService.UpdatePost(r.Context(), permalink, updatedPost)
 	if err != nil {
@@ -63,6 +85,42 @@ func (s *Server) handlePostUpdate(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, fmt.Sprintf("/post/%s", permalink), http.StatusFound)
 }
 
+func (s *Server) handlePostDeleteConfirm(w http.ResponseWriter, r *http.Request) {
+	permalink, ok := mux.Vars(r)["permalink"]
+	if !ok {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing permalink"})
+		return
+	}
+
+	post, err := s.JournalService.FindPostByPermalink(r.Context(), permalink)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "deletepost", post)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handlePostDelete(w http.ResponseWriter, r *http.Request) {
+	permalink, ok := mux.Vars(r)["permalink"]
+	if !ok {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing permalink"})
+		return
+	}
+
+	err := s.JournalService.DeletePost(r.Context(), permalink)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
 func (s *Server) handlePostCreate(w http.ResponseWriter, r *http.Request) {
 	permalink, ok := mux.Vars(r)["permalink"]
 	if !ok {
@@ -83,15 +141,38 @@ func (s *Server) handlePostCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	status := r.Form.Get("status")
+	if status == "" {
+		status = journal.PostStatusDraft
+	}
+
 	post := &journal.Post{
 		Permalink: permalink,
 		Title:     title,
 		Content:   content,
+		Status:    status,
+		Tags:      parseTags(r.Form.Get("tags")),
+	}
+
+	publishAt, err := parsePublishAt(r.Form.Get("publish_at"))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+	if !publishAt.IsZero() {
+		post.PublishAt = &publishAt
+	}
+
+	err = post.Validate()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid post: %v", err)})
+		return
 	}
 
 	err = s.JournalService.CreatePost(r.Context(), post)
 	if err != nil {
 		Error(w, r, err)
+		return
 	}
 
 	http.Redirect(w, r, fmt.Sprintf("/post/%s", permalink), http.StatusFound)
@@ -108,7 +189,11 @@ func (s *Server) handlePostView(w http.ResponseWriter, r *http.Request) {
 	post, err := s.JournalService.FindPostByPermalink(r.Context(), permalink)
 	if errors.As(err, &e) {
 		if e.Code == journal.ENOTFOUND {
-			err = tmpl.ExecuteTemplate(w, "newpost", permalink)
+			if !canEdit(r) {
+				s.handleNotFound(w, r)
+				return
+			}
+			err = render(w, r, "newpost", permalink)
 			if err != nil {
 				Error(w, r, err)
 				return
@@ -121,9 +206,79 @@ func (s *Server) handlePostView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = tmpl.ExecuteTemplate(w, "post", post)
+	// Drafts are only visible to authenticated users
+	if post.Status == journal.PostStatusDraft && journal.UserIDFromContext(r.Context()) == 0 {
+		s.handleNotFound(w, r)
+		return
+	}
+
+	err = render(w, r, "post", post)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handlePostPublish(w http.ResponseWriter, r *http.Request) {
+	permalink, ok := mux.Vars(r)["permalink"]
+	if !ok {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing permalink"})
+		return
+	}
+
+	status := journal.PostStatusPublished
+	err := s.JournalService.UpdatePost(r.Context(), permalink, &journal.PostUpdate{Status: &status})
 	if err != nil {
 		Error(w, r, err)
 		return
 	}
+
+	http.Redirect(w, r, fmt.Sprintf("/post/%s", permalink), http.StatusFound)
+}
+
+func (s *Server) handleDrafts(w http.ResponseWriter, r *http.Request) {
+	status := journal.PostStatusDraft
+	posts, _, err := s.JournalService.FindPosts(r.Context(), &journal.PostFilter{Status: &status})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "drafts", posts)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+// parsePublishAt parses the publish_at form value as UTC. An empty value
+// yields the zero time.
+func parsePublishAt(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.ParseInLocation(publishAtLayout, value, time.UTC)
+	if err != nil {
+		return time.Time{}, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid publish date: %s", value)}
+	}
+
+	return t, nil
+}
+
+// parseTags splits a comma-separated list of tags, normalizing each tag to
+// lowercase and dropping empty and duplicate entries.
+func parseTags(value string) []string {
+	seen := make(map[string]bool)
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
 }
@@ -0,0 +1,173 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	journal "github.com/bertinatto/journal3"
+	"github.com/gorilla/mux"
+)
+
+type postRevisionsData struct {
+	Post      *journal.Post
+	Revisions []*journal.PostRevision
+}
+
+type postDiffData struct {
+	Post *journal.Post
+	From *journal.PostRevision
+	To   *journal.PostRevision
+	revisionDiff
+}
+
+// revisionDiff holds the changes between two versions of a post or page.
+type revisionDiff struct {
+	Title []diffLine
+	Lines []diffLine
+}
+
+func diffRevisions(fromTitle, fromContent, toTitle, toContent string) revisionDiff {
+	return revisionDiff{
+		Title: diffLines(fromTitle, toTitle),
+		Lines: diffLines(fromContent, toContent),
+	}
+}
+
+func (s *Server) handlePostRevisions(w http.ResponseWriter, r *http.Request) {
+	permalink, ok := mux.Vars(r)["permalink"]
+	if !ok {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing permalink"})
+		return
+	}
+
+	post, err := s.JournalService.FindPostByPermalink(r.Context(), permalink)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	revisions, _, err := s.JournalService.FindPostRevisions(r.Context(), &journal.PostRevisionFilter{PostID: &post.ID})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "postrevisions", &postRevisionsData{Post: post, Revisions: revisions})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handlePostDiff(w http.ResponseWriter, r *http.Request) {
+	permalink, ok := mux.Vars(r)["permalink"]
+	if !ok {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing permalink"})
+		return
+	}
+
+	post, err := s.JournalService.FindPostByPermalink(r.Context(), permalink)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	from, err := s.findPostRevision(r, post, r.URL.Query().Get("from"))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	to, err := s.findPostRevision(r, post, r.URL.Query().Get("to"))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "postdiff", &postDiffData{
+		Post:         post,
+		From:         from,
+		To:           to,
+		revisionDiff: diffRevisions(from.Title, from.Content, to.Title, to.Content),
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handlePostRevisionRestore(w http.ResponseWriter, r *http.Request) {
+	permalink, ok := mux.Vars(r)["permalink"]
+	if !ok {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing permalink"})
+		return
+	}
+
+	post, err := s.JournalService.FindPostByPermalink(r.Context(), permalink)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	revision, err := s.findPostRevision(r, post, mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = s.JournalService.UpdatePost(r.Context(), permalink, &journal.PostUpdate{
+		Title:   &revision.Title,
+		Content: &revision.Content,
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/post/%s", permalink), http.StatusFound)
+}
+
+// parseRevisionID parses the ID of a revision. An empty ID or "current"
+// refers to the current version, which has ID 0.
+func parseRevisionID(id string) (int, error) {
+	if id == "" || id == "current" {
+		return 0, nil
+	}
+
+	revisionID, err := strconv.Atoi(id)
+	if err != nil || revisionID <= 0 {
+		return 0, &journal.Error{Code: journal.EBADINPUT, Message: fmt.Sprintf("Invalid revision: %s", id)}
+	}
+	return revisionID, nil
+}
+
+// findPostRevision looks up a revision of post by its ID, as parsed by
+// parseRevisionID.
+func (s *Server) findPostRevision(r *http.Request, post *journal.Post, id string) (*journal.PostRevision, error) {
+	revisionID, err := parseRevisionID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if revisionID == 0 {
+		return &journal.PostRevision{
+			PostID:    post.ID,
+			Title:     post.Title,
+			Content:   post.Content,
+			CreatedAt: post.UpdatedAt,
+		}, nil
+	}
+
+	revision, err := s.JournalService.FindPostRevisionByID(r.Context(), revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't leak revisions from other posts
+	if revision.PostID != post.ID {
+		return nil, &journal.Error{Code: journal.ENOTFOUND, Message: "Revision not found"}
+	}
+
+	return revision, nil
+}
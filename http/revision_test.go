@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+func TestRevisionDiff(t *testing.T) {
+	s, _ := newTestServer(t)
+	ctx := context.Background()
+	createUser(t, s, "Ann", "ann@example.com", "secret1", journal.RoleEditor)
+	cookies := login(t, s, "ann@example.com", "secret1")
+
+	err := s.JournalService.CreatePost(ctx, &journal.Post{Permalink: "hello", Title: "Hello", Content: "one\ntwo", Status: journal.PostStatusPublished})
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	err = s.PageService.CreatePage(ctx, &journal.Page{Name: "about", Title: "About", Content: "one\ntwo"})
+	if err != nil {
+		t.Fatalf("failed to create page: %v", err)
+	}
+	content := "one\nthree"
+	err = s.JournalService.UpdatePost(ctx, "hello", &journal.PostUpdate{Content: &content})
+	if err != nil {
+		t.Fatalf("failed to update post: %v", err)
+	}
+	err = s.PageService.UpdatePage(ctx, "about", &journal.PageUpdate{Content: &content})
+	if err != nil {
+		t.Fatalf("failed to update page: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"post", "/post/hello/diff?from=1&to=current", http.StatusOK},
+		{"page", "/p/about/diff?from=1", http.StatusOK},
+		{"invalid revision", "/post/hello/diff?from=abc", http.StatusBadRequest},
+		{"unknown revision", "/p/about/diff?from=2", http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.target, nil)
+			for _, cookie := range cookies {
+				r.AddCookie(cookie)
+			}
+			resp, body := serve(t, s, r)
+			if resp.StatusCode != tc.status {
+				t.Fatalf("got status %d, expected %d: %s", resp.StatusCode, tc.status, body)
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+			for _, expected := range []string{`class="Diff-delete">- two`, `class="Diff-insert">+ three`} {
+				if !strings.Contains(body, expected) {
+					t.Errorf("expected %q in the diff: %s", expected, body)
+				}
+			}
+		})
+	}
+}
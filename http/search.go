@@ -0,0 +1,48 @@
+package http
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+const searchResultsLimit = 50
+
+type searchData struct {
+	Query   string
+	Results []*journal.SearchResult
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	data := &searchData{
+		Query: strings.TrimSpace(r.URL.Query().Get("q")),
+	}
+
+	if data.Query != "" {
+		results, err := s.SearchService.Search(r.Context(), &journal.SearchFilter{
+			Query: data.Query,
+			Limit: searchResultsLimit,
+		})
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+		data.Results = results
+	}
+
+	err := render(w, r, "search", data)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+// highlight escapes a search snippet and wraps the matched terms in <mark>.
+func highlight(snippet string) template.HTML {
+	escaped := template.HTMLEscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, journal.SearchHighlightStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, journal.SearchHighlightEnd, "</mark>")
+	return template.HTML(escaped)
+}
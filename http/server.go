@@ -1,6 +1,7 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"html/template"
 	"net"
@@ -40,17 +41,67 @@ var tmpl = template.Must(template.New("").Funcs(
 		"toTitle": func(content string) template.HTML {
 			return template.HTML(strings.Title(content))
 		},
+		"join":      strings.Join,
+		"highlight": highlight,
 		"safeHTML": func(content string) template.HTML {
-			parser := parser.NewWithExtensions(parser.CommonExtensions |
-				parser.FencedCode |
-				parser.HardLineBreak |
-				parser.NoEmptyLineBeforeBlock |
-				parser.EmptyLinesBreakList)
-			return template.HTML(markdown.ToHTML([]byte(content), parser, nil))
+			return template.HTML(renderMarkdown(content))
 		},
 	},
 ).ParseFS(html.FS, "*.tmpl"))
 
+// templateData is what every page template is executed with. Data holds
+// the handler's own data, while the other fields are filled in per request
+// by render.
+type templateData struct {
+	Data interface{}
+	Menu []*journal.MenuItem
+
+	csrf *csrfState
+}
+
+// render executes the named template with data wrapped in a templateData,
+// so templates find it under .Data.
+func render(w http.ResponseWriter, r *http.Request, name string, data interface{}) error {
+	td := &templateData{
+		Data: data,
+		Menu: menuFromContext(r.Context()),
+		csrf: csrfFromContext(r.Context()),
+	}
+
+	var buf bytes.Buffer
+	err := tmpl.ExecuteTemplate(&buf, name, td)
+	if err != nil {
+		return err
+	}
+
+	// The template generated a CSRF token, store it before the body is sent
+	if td.csrf != nil && td.csrf.dirty {
+		err = td.csrf.session.Save(r, w)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// renderMarkdown converts markdown content into HTML.
+func renderMarkdown(content string) string {
+	parser := parser.NewWithExtensions(parser.CommonExtensions |
+		parser.FencedCode |
+		parser.HardLineBreak |
+		parser.NoEmptyLineBeforeBlock |
+		parser.EmptyLinesBreakList)
+	return string(markdown.ToHTML([]byte(content), parser, nil))
+}
+
+type indexData struct {
+	Posts      []*journal.Post
+	Tags       []*journal.Tag
+	Pagination Pagination
+}
+
 type Server struct {
 	ln     net.Listener
 	server *http.Server
@@ -59,10 +110,35 @@ type Server struct {
 	Domain string
 	Addr   string
 
-	PageService    journal.PageService
-	JournalService journal.JournalService
-	NowService     journal.NowService
-	UserService    journal.UserService
+	// PublicURL is the absolute URL of the site, without a trailing slash.
+	// Links sent by email are built from it, or from Domain, but never from
+	// the request, whose Host header the client controls.
+	PublicURL string
+
+	// Author is the name credited in the feeds.
+	Author string
+
+	// BehindProxy makes failed logins be tracked by the address the reverse
+	// proxy puts in X-Forwarded-For, instead of the proxy's own. Only set it
+	// when the proxy is the sole way in, otherwise the header can be forged.
+	BehindProxy bool
+
+	// SignupMode is one of SignupModeOpen, SignupModeInvite or
+	// SignupModeClosed. It defaults to SignupModeOpen.
+	SignupMode string
+
+	PageService          journal.PageService
+	MenuService          journal.MenuService
+	JournalService       journal.JournalService
+	NowService           journal.NowService
+	UserService          journal.UserService
+	SearchService        journal.SearchService
+	SessionService       journal.SessionService
+	SessionStore         *SessionStore
+	InvitationService    journal.InvitationService
+	FailedLoginService   journal.FailedLoginService
+	PasswordResetService journal.PasswordResetService
+	Mailer               journal.Mailer
 }
 
 func NewServer() *Server {
@@ -76,16 +152,62 @@ func NewServer() *Server {
 	s.router.PathPrefix("/assets").Handler(http.StripPrefix("/assets", http.FileServer(http.FS(assets.FS))))
 	s.router.PathPrefix("/uploads").Handler(http.StripPrefix("/uploads", http.FileServer(http.Dir("http/upload/"))))
 
+	// Register JSON API routes
+	{
+		api := s.router.PathPrefix("/api/v1").Subrouter()
+		api.Use(s.handleSession)
+		api.Use(s.handleAPIKey)
+		api.Use(s.handleCSRF)
+		api.Use(trackMetrics)
+		api.NotFoundHandler = http.HandlerFunc(s.handleAPINotFound)
+		api.HandleFunc("/posts", s.handleAPIPostList).Methods(http.MethodGet)
+		api.HandleFunc("/posts/{permalink}", s.handleAPIPostGet).Methods(http.MethodGet)
+		api.HandleFunc("/pages", s.handleAPIPageList).Methods(http.MethodGet)
+		api.HandleFunc("/pages/{name}", s.handleAPIPageGet).Methods(http.MethodGet)
+		api.HandleFunc("/now", s.handleAPINowGet).Methods(http.MethodGet)
+
+		r := api.PathPrefix("/").Subrouter()
+		r.Use(s.handleAPIAuth)
+		r.HandleFunc("/users/{id:[0-9]+}", s.handleAPIUserGet).Methods(http.MethodGet)
+		r.HandleFunc("/users/{id:[0-9]+}", s.handleAPIUserUpdate).Methods(http.MethodPatch)
+		r.HandleFunc("/users/{id:[0-9]+}", s.handleAPIUserDelete).Methods(http.MethodDelete)
+
+		editor := r.PathPrefix("/").Subrouter()
+		editor.Use(s.handleRole(journal.RoleEditor))
+		editor.HandleFunc("/posts", s.handleAPIPostCreate).Methods(http.MethodPost)
+		editor.HandleFunc("/posts/{permalink}", s.handleAPIPostUpdate).Methods(http.MethodPatch)
+		editor.HandleFunc("/posts/{permalink}", s.handleAPIPostDelete).Methods(http.MethodDelete)
+		editor.HandleFunc("/pages", s.handleAPIPageCreate).Methods(http.MethodPost)
+		editor.HandleFunc("/pages/{name}", s.handleAPIPageUpdate).Methods(http.MethodPatch)
+		editor.HandleFunc("/pages/{name}", s.handleAPIPageDelete).Methods(http.MethodDelete)
+		editor.HandleFunc("/now", s.handleAPINowCreate).Methods(http.MethodPost)
+
+		admin := r.PathPrefix("/").Subrouter()
+		admin.Use(s.handleRole(journal.RoleAdmin))
+		admin.HandleFunc("/users", s.handleAPIUserList).Methods(http.MethodGet)
+		admin.HandleFunc("/users", s.handleAPIUserCreate).Methods(http.MethodPost)
+	}
+
 	// Public-facing endopoints, except assets and uploads
 	router := s.router.PathPrefix("/").Subrouter()
-	router.Use(s.handleMethodOverride)
 	router.Use(s.handleSession)
+	router.Use(s.handleCSRF)
+	router.Use(s.handleMenu)
 	router.Use(trackMetrics)
 	router.HandleFunc("/", s.handleIndex).Methods(http.MethodGet)
-	router.HandleFunc("/about", s.handleAboutView).Methods(http.MethodGet)
-	router.HandleFunc("/contact", s.handleContactView).Methods(http.MethodGet)
+	router.HandleFunc("/about", handlePageRedirect("about")).Methods(http.MethodGet)
+	router.HandleFunc("/contact", handlePageRedirect("contact")).Methods(http.MethodGet)
+	router.HandleFunc("/p/{name}", s.handlePageView).Methods(http.MethodGet)
 	router.HandleFunc("/now", s.handleNowView).Methods(http.MethodGet)
+	router.HandleFunc("/now/archive", s.handleNowArchive).Methods(http.MethodGet)
+	router.HandleFunc("/now/map", s.handleNowMap).Methods(http.MethodGet)
+	router.HandleFunc("/now/{id:[0-9]+}", s.handleNowEntryView).Methods(http.MethodGet)
 	router.HandleFunc("/post/{permalink}", s.handlePostView).Methods(http.MethodGet)
+	router.HandleFunc("/tag/{name}", s.handleTagView).Methods(http.MethodGet)
+	router.HandleFunc("/search", s.handleSearch).Methods(http.MethodGet)
+	router.HandleFunc("/feed.atom", s.handleFeedAtom).Methods(http.MethodGet)
+	router.HandleFunc("/feed.rss", s.handleFeedRSS).Methods(http.MethodGet)
+	router.HandleFunc("/feed.json", s.handleFeedJSON).Methods(http.MethodGet)
 
 	// Register routes that require the user to NOT be authenticated
 	{
@@ -95,6 +217,12 @@ func NewServer() *Server {
 		r.HandleFunc("/signup", s.handleSingUp).Methods(http.MethodPost)
 		r.HandleFunc("/login", s.handleLoginView).Methods(http.MethodGet)
 		r.HandleFunc("/login", s.handleLoginCreate).Methods(http.MethodPost)
+		r.HandleFunc("/login/totp", s.handleLoginTOTPView).Methods(http.MethodGet)
+		r.HandleFunc("/login/totp", s.handleLoginTOTP).Methods(http.MethodPost)
+		r.HandleFunc("/password/forgot", s.handlePasswordForgotView).Methods(http.MethodGet)
+		r.HandleFunc("/password/forgot", s.handlePasswordForgot).Methods(http.MethodPost)
+		r.HandleFunc("/password/reset", s.handlePasswordResetView).Methods(http.MethodGet)
+		r.HandleFunc("/password/reset", s.handlePasswordReset).Methods(http.MethodPost)
 	}
 
 	// Register routes that require authentication
@@ -102,20 +230,72 @@ func NewServer() *Server {
 		r := router.PathPrefix("/").Subrouter()
 		r.Use(s.handleAuth)
 		r.HandleFunc("/logout", s.handleLogout).Methods(http.MethodGet)
-		r.HandleFunc("/about", s.handleAboutCreate).Methods(http.MethodPost)
-		r.HandleFunc("/about/edit", s.handleAboutEdit).Methods(http.MethodGet)
-		r.HandleFunc("/about", s.handleAboutUpdate).Methods(http.MethodPatch)
-		r.HandleFunc("/contact", s.handleContactCreate).Methods(http.MethodPost)
-		r.HandleFunc("/contact/edit", s.handleContactEdit).Methods(http.MethodGet)
-		r.HandleFunc("/contact", s.handleContactUpdate).Methods(http.MethodPatch)
-		r.HandleFunc("/now", s.handleNowCreate).Methods(http.MethodPost)
-		r.HandleFunc("/now/edit", s.handleNowEdit).Methods(http.MethodGet)
-		r.HandleFunc("/post/{permalink}/edit", s.handlePostEdit).Methods(http.MethodGet)
-		r.HandleFunc("/post/{permalink}/edit", s.handlePostUpdate).Methods(http.MethodPatch)
-		r.HandleFunc("/post/{permalink}", s.handlePostCreate).Methods(http.MethodPost)
+		r.HandleFunc("/post/{permalink}/revisions", s.handlePostRevisions).Methods(http.MethodGet)
+		r.HandleFunc("/post/{permalink}/diff", s.handlePostDiff).Methods(http.MethodGet)
+		r.HandleFunc("/p/{name}/revisions", s.handlePageRevisions).Methods(http.MethodGet)
+		r.HandleFunc("/p/{name}/diff", s.handlePageDiff).Methods(http.MethodGet)
+		r.HandleFunc("/drafts", s.handleDrafts).Methods(http.MethodGet)
+		r.HandleFunc("/account", s.handleAccountView).Methods(http.MethodGet)
+		r.HandleFunc("/account", s.handleAccountUpdate).Methods(http.MethodPatch)
+		r.HandleFunc("/account", s.handleAccountDelete).Methods(http.MethodDelete)
+		r.HandleFunc("/account/password", s.handleAccountPassword).Methods(http.MethodPost)
+		r.HandleFunc("/account/delete", s.handleAccountDeleteConfirm).Methods(http.MethodGet)
+		r.HandleFunc("/settings", s.handleSettingsView).Methods(http.MethodGet)
+		r.HandleFunc("/settings/apikey", s.handleAPIKeyRotate).Methods(http.MethodPost)
+		r.HandleFunc("/settings/apikey", s.handleAPIKeyRevoke).Methods(http.MethodDelete)
+		r.HandleFunc("/settings/sessions/{id}", s.handleSessionRevoke).Methods(http.MethodDelete)
+		r.HandleFunc("/settings/totp", s.handleTOTPSetup).Methods(http.MethodGet)
+		r.HandleFunc("/settings/totp", s.handleTOTPEnable).Methods(http.MethodPost)
+		r.HandleFunc("/settings/totp", s.handleTOTPDisable).Methods(http.MethodDelete)
+		r.HandleFunc("/settings/totp/recovery", s.handleRecoveryCodesRegenerate).Methods(http.MethodPost)
+
+		// Register routes that require the editor role
+		{
+			r := r.PathPrefix("/").Subrouter()
+			r.Use(s.handleRole(journal.RoleEditor))
+			r.HandleFunc("/p/{name}", s.handlePageCreate).Methods(http.MethodPost)
+			r.HandleFunc("/p/{name}/edit", s.handlePageEdit).Methods(http.MethodGet)
+			r.HandleFunc("/p/{name}", s.handlePageUpdate).Methods(http.MethodPatch)
+			r.HandleFunc("/p/{name}/delete", s.handlePageDeleteConfirm).Methods(http.MethodGet)
+			r.HandleFunc("/p/{name}", s.handlePageDelete).Methods(http.MethodDelete)
+			r.HandleFunc("/p/{name}/revisions/{id}/restore", s.handlePageRevisionRestore).Methods(http.MethodPost)
+			r.HandleFunc("/now", s.handleNowCreate).Methods(http.MethodPost)
+			r.HandleFunc("/now/edit", s.handleNowEdit).Methods(http.MethodGet)
+			r.HandleFunc("/post/{permalink}/edit", s.handlePostEdit).Methods(http.MethodGet)
+			r.HandleFunc("/post/{permalink}/edit", s.handlePostUpdate).Methods(http.MethodPatch)
+			r.HandleFunc("/post/{permalink}", s.handlePostCreate).Methods(http.MethodPost)
+			r.HandleFunc("/post/{permalink}/delete", s.handlePostDeleteConfirm).Methods(http.MethodGet)
+			r.HandleFunc("/post/{permalink}", s.handlePostDelete).Methods(http.MethodDelete)
+			r.HandleFunc("/post/{permalink}/publish", s.handlePostPublish).Methods(http.MethodPost)
+			r.HandleFunc("/post/{permalink}/revisions/{id}/restore", s.handlePostRevisionRestore).Methods(http.MethodPost)
+		}
+
+		// Register routes that require the admin role
+		{
+			r := r.PathPrefix("/").Subrouter()
+			r.Use(s.handleRole(journal.RoleAdmin))
+			r.HandleFunc("/admin/users", s.handleAdminUsers).Methods(http.MethodGet)
+			r.HandleFunc("/admin/users/{id:[0-9]+}", s.handleAdminUser).Methods(http.MethodGet)
+			r.HandleFunc("/admin/users/{id:[0-9]+}", s.handleAdminUserUpdate).Methods(http.MethodPatch)
+			r.HandleFunc("/admin/users/{id:[0-9]+}", s.handleAdminUserDelete).Methods(http.MethodDelete)
+			r.HandleFunc("/admin/users/{id:[0-9]+}/delete", s.handleAdminUserDeleteConfirm).Methods(http.MethodGet)
+			r.HandleFunc("/admin/users/{id:[0-9]+}/reset", s.handleAdminUserReset).Methods(http.MethodPost)
+			r.HandleFunc("/admin/invitations", s.handleInvitations).Methods(http.MethodGet)
+			r.HandleFunc("/admin/invitations", s.handleInvitationCreate).Methods(http.MethodPost)
+			r.HandleFunc("/admin/invitations/{id:[0-9]+}", s.handleInvitationDelete).Methods(http.MethodDelete)
+			r.HandleFunc("/admin/pages", s.handleAdminPages).Methods(http.MethodGet)
+			r.HandleFunc("/admin/pages", s.handlePageCreate).Methods(http.MethodPost)
+			r.HandleFunc("/admin/menu", s.handleAdminMenu).Methods(http.MethodGet)
+			r.HandleFunc("/admin/menu", s.handleAdminMenuCreate).Methods(http.MethodPost)
+			r.HandleFunc("/admin/menu/{id:[0-9]+}", s.handleAdminMenuUpdate).Methods(http.MethodPatch)
+			r.HandleFunc("/admin/menu/{id:[0-9]+}", s.handleAdminMenuDelete).Methods(http.MethodDelete)
+			r.HandleFunc("/admin/logins", s.handleFailedLogins).Methods(http.MethodGet)
+			r.HandleFunc("/admin/logins/unlock", s.handleAccountUnlock).Methods(http.MethodPost)
+		}
 	}
 
-	s.server.Handler = http.HandlerFunc(s.router.ServeHTTP)
+	// Method override must run before the router matches a route
+	s.server.Handler = s.handleMethodOverride(s.router)
 	return s
 }
 
@@ -143,6 +323,28 @@ func (s *Server) TLS() bool {
 	return s.Domain != ""
 }
 
+// BaseURL returns the absolute URL of the site, without a trailing slash.
+// It falls back to the request host when no URL or domain is configured, so
+// it must not be used for links sent by email.
+func (s *Server) BaseURL(r *http.Request) string {
+	if u := s.configuredURL(); u != "" {
+		return u
+	}
+	return "http://" + r.Host
+}
+
+// configuredURL returns the absolute URL of the site as configured, or an
+// empty string if neither PublicURL nor Domain is set.
+func (s *Server) configuredURL() string {
+	if s.PublicURL != "" {
+		return s.PublicURL
+	}
+	if s.TLS() {
+		return "https://" + s.Domain
+	}
+	return ""
+}
+
 func (s *Server) handlePanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -160,12 +362,14 @@ func (s *Server) handlePanic(next http.Handler) http.Handler {
 func (s *Server) handleSession(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Most requests won't have a session
-		session, _ := store.Get(r, sessionCookie)
+		session, _ := s.SessionStore.Get(r, sessionCookie)
 		if auth, ok := session.Values["authenticated"].(bool); ok && auth {
 			if id, ok := session.Values["uid"].(int); ok && id > 0 {
 				user, err := s.UserService.FindUserByID(r.Context(), id)
 				if err != nil {
-					klog.Errorf("Could not find user %d: %v", err)
+					klog.Errorf("Could not find user %d: %v", id, err)
+				} else if user.Disabled {
+					klog.Infof("Ignoring session of disabled user %d", user.ID)
 				} else {
 					r = r.WithContext(journal.NewContextWithUser(r.Context(), user))
 				}
@@ -199,7 +403,7 @@ func (s *Server) handleAuth(next http.Handler) http.Handler {
 			redirect = r.URL.Path
 		}
 
-		session, _ := store.Get(r, sessionCookie)
+		session, _ := s.SessionStore.Get(r, sessionCookie)
 		session.Values["redirect"] = redirect
 		session.Save(r, w)
 
@@ -211,7 +415,7 @@ func (s *Server) handleMethodOverride(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			method := r.PostFormValue("_method")
-			if method == http.MethodPut || method == http.MethodPatch {
+			if method == http.MethodPut || method == http.MethodPatch || method == http.MethodDelete {
 				r.Method = method
 			}
 		}
@@ -220,7 +424,7 @@ func (s *Server) handleMethodOverride(next http.Handler) http.Handler {
 }
 
 func (s *Server) handleNotFound(w http.ResponseWriter, r *http.Request) {
-	err := tmpl.ExecuteTemplate(w, "notfound", nil)
+	err := render(w, r, "notfound", nil)
 	if err != nil {
 		Error(w, r, err)
 		return
@@ -228,12 +432,29 @@ func (s *Server) handleNotFound(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
-	posts, err := s.JournalService.FindPosts(r.Context())
+	page := pageFromRequest(r)
+	status := journal.PostStatusPublished
+	posts, n, err := s.JournalService.FindPosts(r.Context(), &journal.PostFilter{
+		Status: &status,
+		Offset: (page - 1) * postsPerPage,
+		Limit:  postsPerPage,
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	tags, err := s.JournalService.FindTags(r.Context())
 	if err != nil {
 		Error(w, r, err)
 		return
 	}
-	err = tmpl.ExecuteTemplate(w, "index", posts)
+
+	err = render(w, r, "index", &indexData{
+		Posts:      posts,
+		Tags:       tags,
+		Pagination: newPagination(page, postsPerPage, n),
+	})
 	if err != nil {
 		Error(w, r, err)
 		return
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	journal "github.com/bertinatto/journal3"
+	"github.com/bertinatto/journal3/sqlite"
+)
+
+// newTestServer returns a server backed by a fresh database.
+func newTestServer(t *testing.T) (*Server, *sqlite.DB) {
+	t.Helper()
+
+	db := sqlite.NewDB(filepath.Join(t.TempDir(), "data.db"))
+	err := db.Open()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	s := NewServer()
+	s.PageService = sqlite.NewPageService(db)
+	s.JournalService = sqlite.NewJournalService(db)
+	s.NowService = sqlite.NewNowService(db)
+	s.UserService = sqlite.NewUserService(db)
+	s.SearchService = sqlite.NewSearchService(db)
+	s.SessionService = sqlite.NewSessionService(db)
+	s.SessionStore = NewSessionStore(s.SessionService, "secret")
+	s.InvitationService = sqlite.NewInvitationService(db)
+	s.FailedLoginService = sqlite.NewFailedLoginService(db)
+	s.MenuService = sqlite.NewMenuService(db)
+	s.PasswordResetService = sqlite.NewPasswordResetService(db)
+	s.Mailer = &testMailer{}
+	return s, db
+}
+
+// testMailer keeps the messages it is asked to send, or fails with err.
+type testMailer struct {
+	messages []*journal.Message
+	err      error
+}
+
+func (m *testMailer) SendMail(ctx context.Context, msg *journal.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// serve sends the request to the server and returns the response and its
+// body.
+func serve(t *testing.T, s *Server, r *http.Request) (*http.Response, string) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	resp := w.Result()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	return resp, string(body)
+}
+
+// createUser stores a user with the given password and role.
+func createUser(t *testing.T, s *Server, name, email, password, role string) *journal.User {
+	t.Helper()
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	user := &journal.User{Name: name, Email: email, Password: hash, Role: role}
+	err = s.UserService.CreateUser(context.Background(), user)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+// login signs in through the login form and returns the session cookies.
+func login(t *testing.T, s *Server, email, password string) []*http.Cookie {
+	t.Helper()
+
+	token, cookies := fetchCSRFToken(t, s, "/login", nil)
+	form := url.Values{"email": {email}, "password": {password}, csrfFormField: {token}}
+	resp, body := serve(t, s, formRequest("POST", "/login", form, cookies))
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("failed to log in, got status %d: %s", resp.StatusCode, body)
+	}
+	return mergeCookies(cookies, resp.Cookies())
+}
+
+// formRequest returns a form submission carrying the given cookies.
+func formRequest(method, target string, form url.Values, cookies []*http.Cookie) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+	return r
+}
+
+var csrfFieldRegexp = regexp.MustCompile(`name="` + csrfFormField + `" value="([^"]+)"`)
+
+// fetchCSRFToken loads a page with a form and returns the CSRF token in it,
+// along with the cookies of the session holding the token.
+func fetchCSRFToken(t *testing.T, s *Server, target string, cookies []*http.Cookie) (string, []*http.Cookie) {
+	t.Helper()
+
+	r := httptest.NewRequest("GET", target, nil)
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+	resp, body := serve(t, s, r)
+	m := csrfFieldRegexp.FindStringSubmatch(body)
+	if m == nil {
+		t.Fatalf("no CSRF token in %s, got status %d", target, resp.StatusCode)
+	}
+	return m[1], mergeCookies(cookies, resp.Cookies())
+}
+
+// mergeCookies returns the cookies with the ones set by a response
+// replacing those of the same name.
+func mergeCookies(cookies, set []*http.Cookie) []*http.Cookie {
+	merged := make([]*http.Cookie, 0, len(cookies)+len(set))
+	for _, cookie := range cookies {
+		replaced := false
+		for _, c := range set {
+			replaced = replaced || c.Name == cookie.Name
+		}
+		if !replaced {
+			merged = append(merged, cookie)
+		}
+	}
+	return append(merged, set...)
+}
@@ -0,0 +1,246 @@
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"k8s.io/klog/v2"
+)
+
+const (
+	sessionCookie        = "journal3-session"
+	sessionMaxAge        = 60 * 60 * 24 // 24 hours
+	sessionCleanupPeriod = 10 * time.Minute
+)
+
+var _ sessions.Store = (*SessionStore)(nil)
+
+// SessionStore is a gorilla/sessions store that keeps session values in a
+// journal.SessionService. The cookie only carries the session ID, signed and
+// encrypted with the configured keys.
+type SessionStore struct {
+	service journal.SessionService
+	codecs  []securecookie.Codec
+
+	Options *sessions.Options
+}
+
+// NewSessionStore returns a store using the given secrets to derive the
+// cookie signing and encryption keys. The first secret is used to encode
+// cookies and every secret is accepted when decoding, so keys can be rotated
+// by prepending a new secret and dropping old ones later.
+func NewSessionStore(service journal.SessionService, secrets ...string) *SessionStore {
+	keyPairs := make([][]byte, 0, len(secrets)*2)
+	for _, secret := range secrets {
+		keyPairs = append(keyPairs,
+			deriveKey(secret, "journal3 session signing"),
+			deriveKey(secret, "journal3 session encryption"))
+	}
+
+	codecs := securecookie.CodecsFromPairs(keyPairs...)
+	for _, codec := range codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(sessionMaxAge)
+		}
+	}
+
+	return &SessionStore{
+		service: service,
+		codecs:  codecs,
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   sessionMaxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		},
+	}
+}
+
+// GenerateSessionSecret returns a random secret, for when none is configured.
+func GenerateSessionSecret() (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// deriveKey derives a 32-byte key from secret for the given purpose.
+func deriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+func (s *SessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *SessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		// No cookie, nothing to load
+		return session, nil
+	}
+
+	// Cookies signed with a key that has since been dropped can't be decoded,
+	// treat them as if there was no session at all
+	var id string
+	err = securecookie.DecodeMulti(name, c.Value, &id, s.codecs...)
+	if err != nil {
+		klog.V(2).Infof("Ignoring invalid session cookie: %v", err)
+		return session, nil
+	}
+
+	stored, err := s.service.FindSessionByID(r.Context(), id)
+	if journal.ErrorCode(err) == journal.ENOTFOUND {
+		// Expired or revoked, start over
+		return session, nil
+	} else if err != nil {
+		return session, err
+	}
+
+	err = securecookie.GobEncoder{}.Deserialize(stored.Data, &session.Values)
+	if err != nil {
+		return session, err
+	}
+
+	session.ID = stored.ID
+	session.IsNew = false
+	return session, nil
+}
+
+func (s *SessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	// A negative MaxAge deletes the session
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			err := s.service.DeleteSession(r.Context(), session.ID)
+			if err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	data, err := securecookie.GobEncoder{}.Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+
+	uid, _ := session.Values["uid"].(int)
+	expiresAt := time.Now().Add(time.Duration(session.Options.MaxAge) * time.Second).UTC().Truncate(time.Second)
+
+	if session.ID == "" {
+		id, err := GenerateSessionSecret()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+
+		err = s.service.CreateSession(r.Context(), &journal.Session{
+			ID:        session.ID,
+			UserID:    uid,
+			Data:      data,
+			UserAgent: r.UserAgent(),
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		err = s.service.UpdateSession(r.Context(), session.ID, &journal.SessionUpdate{
+			UserID:    &uid,
+			Data:      &data,
+			ExpiresAt: &expiresAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Renew drops the stored session and gives it a new ID on the next save, so
+// a session ID from before signing in can't be reused afterwards.
+func (s *SessionStore) Renew(r *http.Request, session *sessions.Session) error {
+	if session.ID != "" {
+		err := s.service.DeleteSession(r.Context(), session.ID)
+		if err != nil {
+			return err
+		}
+	}
+	session.ID = ""
+	delete(session.Values, csrfSessionKey)
+	return nil
+}
+
+// Cleanup periodically deletes expired sessions, and failed logins too old
+// to count towards throttling, until ctx is cancelled.
+func (s *Server) Cleanup(ctx context.Context) {
+	ticker := time.NewTicker(sessionCleanupPeriod)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.SessionService.DeleteExpiredSessions(ctx)
+		if err != nil && ctx.Err() == nil {
+			klog.Errorf("Failed to delete expired sessions: %v", err)
+		} else if n > 0 {
+			klog.Infof("Deleted %d expired session(s)", n)
+		}
+
+		n, err = s.FailedLoginService.DeleteFailedLoginsBefore(ctx, time.Now().Add(-loginWindow))
+		if err != nil && ctx.Err() == nil {
+			klog.Errorf("Failed to delete old failed logins: %v", err)
+		} else if n > 0 {
+			klog.Infof("Deleted %d old failed login(s)", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deleteUserSessions signs the user out of every session but the one with
+// the except ID, which may be empty.
+func (s *Server) deleteUserSessions(ctx context.Context, userID int, except string) error {
+	sessions, _, err := s.SessionService.FindSessions(ctx, &journal.SessionFilter{UserID: &userID})
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.ID == except {
+			continue
+		}
+		err = s.SessionService.DeleteSession(ctx, session.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"net/http"
+
+	journal "github.com/bertinatto/journal3"
+	"github.com/gorilla/mux"
+)
+
+type settingsData struct {
+	User             *journal.User
+	Sessions         []*journal.Session
+	CurrentSessionID string
+	RecoveryCodes    int
+
+	// NewAPIKey is only set right after a key is generated
+	NewAPIKey string
+}
+
+func (s *Server) renderSettings(w http.ResponseWriter, r *http.Request, newAPIKey string) {
+	user := journal.UserFromContext(r.Context())
+	sessions, _, err := s.SessionService.FindSessions(r.Context(), &journal.SessionFilter{UserID: &user.ID})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	recoveryCodes, err := s.UserService.CountRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	session, _ := s.SessionStore.Get(r, sessionCookie)
+
+	err = render(w, r, "settings", &settingsData{
+		User:             user,
+		Sessions:         sessions,
+		CurrentSessionID: session.ID,
+		RecoveryCodes:    recoveryCodes,
+		NewAPIKey:        newAPIKey,
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleSettingsView(w http.ResponseWriter, r *http.Request) {
+	s.renderSettings(w, r, "")
+}
+
+func (s *Server) handleAPIKeyRotate(w http.ResponseWriter, r *http.Request) {
+	user := journal.UserFromContext(r.Context())
+
+	apiKey, err := generateToken()
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	hashed := hashToken(apiKey)
+	err = s.UserService.UpdateUser(r.Context(), user.ID, &journal.UserUpdate{APIKey: &hashed})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+	user.APIKey = hashed
+
+	s.renderSettings(w, r, apiKey)
+}
+
+func (s *Server) handleAPIKeyRevoke(w http.ResponseWriter, r *http.Request) {
+	user := journal.UserFromContext(r.Context())
+
+	empty := ""
+	err := s.UserService.UpdateUser(r.Context(), user.ID, &journal.UserUpdate{APIKey: &empty})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings", http.StatusFound)
+}
+
+func (s *Server) handleSessionRevoke(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// Users may only revoke their own sessions
+	session, err := s.SessionService.FindSessionByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+	if session.UserID != journal.UserIDFromContext(r.Context()) {
+		Error(w, r, &journal.Error{Code: journal.ENOTFOUND, Message: "Session not found"})
+		return
+	}
+
+	err = s.SessionService.DeleteSession(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings", http.StatusFound)
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	journal "github.com/bertinatto/journal3"
+	"github.com/gorilla/mux"
+)
+
+type tagData struct {
+	Name  string
+	Posts []*journal.Post
+}
+
+func (s *Server) handleTagView(w http.ResponseWriter, r *http.Request) {
+	name, ok := mux.Vars(r)["name"]
+	if !ok {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing tag"})
+		return
+	}
+
+	// Tags are stored in lowercase, see parseTags
+	if lower := strings.ToLower(name); lower != name {
+		http.Redirect(w, r, "/tag/"+url.PathEscape(lower), http.StatusMovedPermanently)
+		return
+	}
+
+	status := journal.PostStatusPublished
+	posts, n, err := s.JournalService.FindPosts(r.Context(), &journal.PostFilter{Status: &status, Tag: &name})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if n == 0 {
+		Error(w, r, &journal.Error{Code: journal.ENOTFOUND, Message: "There are no posts with this tag"})
+		return
+	}
+
+	err = render(w, r, "tag", &tagData{Name: name, Posts: posts})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
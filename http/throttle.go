@@ -0,0 +1,181 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+const (
+	// Failed logins older than loginWindow are not counted
+	loginWindow = 24 * time.Hour
+
+	// Once an account or an IP reaches its threshold of failures, each new
+	// failure doubles the wait before the next attempt, from loginBaseDelay
+	// up to loginMaxDelay.
+	accountLoginThreshold = 5
+	ipLoginThreshold      = 20
+	loginBaseDelay        = time.Minute
+	loginMaxDelay         = time.Hour
+
+	failedLoginsPerPage = 50
+)
+
+// loginDelay returns how long to wait after the latest failure, given the
+// number of recent failures and the threshold they are checked against.
+func loginDelay(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+
+	delay := loginBaseDelay
+	for i := threshold; i < failures && delay < loginMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > loginMaxDelay {
+		delay = loginMaxDelay
+	}
+	return delay
+}
+
+// clientIP returns the IP address of the client that sent the request.
+// Behind a reverse proxy that is the last address in X-Forwarded-For, the
+// one added by the proxy itself, since the client controls the rest.
+func (s *Server) clientIP(r *http.Request) string {
+	if s.BehindProxy {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); net.ParseIP(ip) != nil {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// normalizeEmail is used so that throttling can't be bypassed by changing
+// the case of the email address.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// checkLoginThrottle returns an error if either the account or the IP
+// address had too many failed logins recently.
+func (s *Server) checkLoginThrottle(ctx context.Context, email, ip string) error {
+	email = normalizeEmail(email)
+	since := time.Now().Add(-loginWindow)
+
+	for _, check := range []struct {
+		filter    *journal.FailedLoginFilter
+		threshold int
+		message   string
+	}{
+		{&journal.FailedLoginFilter{Email: &email, Since: &since, Limit: 1}, accountLoginThreshold, "This account is temporarily locked"},
+		{&journal.FailedLoginFilter{IP: &ip, Since: &since, Limit: 1}, ipLoginThreshold, "Too many failed logins"},
+	} {
+		logins, n, err := s.FailedLoginService.FindFailedLogins(ctx, check.filter)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+
+		wait := time.Until(logins[0].CreatedAt.Add(loginDelay(n, check.threshold)))
+		if wait > 0 {
+			return &journal.Error{
+				Code:    journal.ETOOMANY,
+				Message: fmt.Sprintf("%s, try again in %s", check.message, wait.Round(time.Second)),
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) recordFailedLogin(ctx context.Context, email, ip string) error {
+	return s.FailedLoginService.CreateFailedLogin(ctx, &journal.FailedLogin{
+		Email: normalizeEmail(email),
+		IP:    ip,
+	})
+}
+
+type lockedAccount struct {
+	Email    string
+	Failures int
+	Until    time.Time
+}
+
+type failedLoginsData struct {
+	Locked     []*lockedAccount
+	Logins     []*journal.FailedLogin
+	Pagination Pagination
+}
+
+func (s *Server) handleFailedLogins(w http.ResponseWriter, r *http.Request) {
+	since := time.Now().Add(-loginWindow)
+	counts, err := s.FailedLoginService.FindFailedLoginCounts(r.Context(), since, accountLoginThreshold)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	locked := make([]*lockedAccount, 0)
+	for _, count := range counts {
+		until := count.LastAt.Add(loginDelay(count.Count, accountLoginThreshold))
+		if until.After(time.Now()) {
+			locked = append(locked, &lockedAccount{Email: count.Email, Failures: count.Count, Until: until})
+		}
+	}
+
+	page := pageFromRequest(r)
+	logins, n, err := s.FailedLoginService.FindFailedLogins(r.Context(), &journal.FailedLoginFilter{
+		Since:  &since,
+		Offset: (page - 1) * failedLoginsPerPage,
+		Limit:  failedLoginsPerPage,
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "failedlogins", &failedLoginsData{
+		Locked:     locked,
+		Logins:     logins,
+		Pagination: newPagination(page, failedLoginsPerPage, n),
+	})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleAccountUnlock(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"})
+		return
+	}
+
+	email := r.Form.Get("email")
+	if email == "" {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Missing email"})
+		return
+	}
+
+	err = s.FailedLoginService.DeleteFailedLogins(r.Context(), normalizeEmail(email))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/logins", http.StatusFound)
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+func TestLoginDelay(t *testing.T) {
+	for _, tc := range []struct {
+		failures int
+		delay    time.Duration
+	}{
+		{0, 0},
+		{accountLoginThreshold - 1, 0},
+		{accountLoginThreshold, loginBaseDelay},
+		{accountLoginThreshold + 1, 2 * loginBaseDelay},
+		{accountLoginThreshold + 2, 4 * loginBaseDelay},
+		{accountLoginThreshold + 100, loginMaxDelay},
+	} {
+		if delay := loginDelay(tc.failures, accountLoginThreshold); delay != tc.delay {
+			t.Errorf("got delay %s after %d failures, expected %s", delay, tc.failures, tc.delay)
+		}
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	s, _ := newTestServer(t)
+	createUser(t, s, "Ann", "ann@example.com", "secret1", journal.RoleEditor)
+	createUser(t, s, "Bob", "bob@example.com", "secret2", journal.RoleEditor)
+
+	token, cookies := fetchCSRFToken(t, s, "/login", nil)
+	attempt := func(email, password string) int {
+		form := url.Values{"email": {email}, "password": {password}, csrfFormField: {token}}
+		resp, _ := serve(t, s, formRequest("POST", "/login", form, cookies))
+		return resp.StatusCode
+	}
+
+	for i := 0; i < accountLoginThreshold; i++ {
+		if status := attempt("ann@example.com", "wrong"); status != http.StatusUnauthorized {
+			t.Fatalf("got status %d for a wrong password, expected %d", status, http.StatusUnauthorized)
+		}
+	}
+
+	// The right password doesn't help, whatever the case of the email
+	for _, email := range []string{"ann@example.com", "ANN@example.com"} {
+		if status := attempt(email, "secret1"); status != http.StatusTooManyRequests {
+			t.Errorf("got status %d logging in as %s, expected %d", status, email, http.StatusTooManyRequests)
+		}
+	}
+
+	// Other accounts from the same address are still let in
+	if status := attempt("bob@example.com", "secret2"); status != http.StatusSeeOther {
+		t.Errorf("got status %d for another account, expected %d", status, http.StatusSeeOther)
+	}
+
+	// An admin unlocking the account lets it in again
+	err := s.FailedLoginService.DeleteFailedLogins(context.Background(), "ann@example.com")
+	if err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	token, cookies = fetchCSRFToken(t, s, "/login", nil)
+	if status := attempt("ann@example.com", "secret1"); status != http.StatusSeeOther {
+		t.Errorf("got status %d after unlocking, expected %d", status, http.StatusSeeOther)
+	}
+}
+
+func TestFailedLoginsView(t *testing.T) {
+	s, _ := newTestServer(t)
+	ctx := context.Background()
+	createUser(t, s, "Ann", "ann@example.com", "secret1", journal.RoleAdmin)
+	cookies := login(t, s, "ann@example.com", "secret1")
+
+	// Bob and Eve are locked out but Carol isn't, and the attempts fill two pages
+	for i := 0; i < failedLoginsPerPage; i++ {
+		email := "eve@example.com"
+		if i < accountLoginThreshold {
+			email = "bob@example.com"
+		}
+		err := s.FailedLoginService.CreateFailedLogin(ctx, &journal.FailedLogin{Email: email, IP: "192.0.2.1"})
+		if err != nil {
+			t.Fatalf("failed to create failed login: %v", err)
+		}
+	}
+	err := s.FailedLoginService.CreateFailedLogin(ctx, &journal.FailedLogin{Email: "carol@example.com", IP: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("failed to create failed login: %v", err)
+	}
+
+	for _, tc := range []struct {
+		url     string
+		present []string
+		absent  []string
+	}{
+		{
+			url:     "/admin/logins",
+			present: []string{`name="email" value="bob@example.com"`, `name="email" value="eve@example.com"`, "carol@example.com", `href="?page=2"`},
+			absent:  []string{`value="carol@example.com"`},
+		},
+		{
+			url:     "/admin/logins?page=2",
+			present: []string{`href="?page=1"`, "<td>bob@example.com</td>"},
+			absent:  []string{"carol@example.com", `href="?page=3"`},
+		},
+	} {
+		t.Run(tc.url, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.url, nil)
+			for _, cookie := range cookies {
+				r.AddCookie(cookie)
+			}
+			resp, body := serve(t, s, r)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("got status %d: %s", resp.StatusCode, body)
+			}
+			for _, s := range tc.present {
+				if !strings.Contains(body, s) {
+					t.Errorf("expected %q in the page", s)
+				}
+			}
+			for _, s := range tc.absent {
+				if strings.Contains(body, s) {
+					t.Errorf("didn't expect %q in the page", s)
+				}
+			}
+		})
+	}
+}
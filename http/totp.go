@@ -0,0 +1,325 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+	"rsc.io/qr"
+)
+
+// TOTP parameters from RFC 6238. These are the defaults every authenticator
+// app supports, so they are not configurable.
+const (
+	totpPeriod = 30
+	totpDigits = 6
+
+	// Codes from the previous and next periods are accepted too, to allow
+	// for clock drift between the server and the user's device
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+
+	// How long users have to enter their code after their password
+	totpLoginTimeout = 5 * time.Minute
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateTOTPSecret returns a random secret, encoded in base32 as expected
+// by authenticator apps.
+func generateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", &journal.Error{Code: journal.EINTERNAL, Message: "Failed to generate secret"}
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// hotp computes the HOTP value of RFC 4226 for key and counter.
+func hotp(key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// totpCode returns the code for secret at time t.
+func totpCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, uint64(t.Unix()/totpPeriod), totpDigits), nil
+}
+
+// validateTOTP reports whether code is valid for secret at time t, and if so
+// the time step it belongs to. Callers must reject steps that were already
+// used, or a code could be replayed while it's still valid.
+func validateTOTP(secret, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	for i := -totpSkew; i <= totpSkew; i++ {
+		at := t.Add(time.Duration(i*totpPeriod) * time.Second)
+		expected, err := totpCode(secret, at)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return at.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+// totpURI returns the otpauth URI that authenticator apps scan to enroll.
+func totpURI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + v.Encode()
+}
+
+// qrCodeDataURL encodes text as a QR code, in a PNG image inlined in a data
+// URL, so enrolling doesn't depend on any external service.
+func qrCodeDataURL(text string) (template.URL, error) {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return "", err
+	}
+	code.Scale = 4
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(code.PNG())), nil
+}
+
+// generateRecoveryCodes returns new random recovery codes, formatted to be
+// easy to copy by hand.
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 10)
+		_, err := rand.Read(buf)
+		if err != nil {
+			return nil, &journal.Error{Code: journal.EINTERNAL, Message: "Failed to generate recovery codes"}
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(buf))
+		codes[i] = code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16]
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode hashes a recovery code for storage. Codes are hashed
+// ignoring case, spaces and dashes, since users type them in by hand.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashToken(code)
+}
+
+// replaceRecoveryCodes generates and stores a new set of recovery codes for
+// the user, returning them so they can be shown once.
+func (s *Server) replaceRecoveryCodes(r *http.Request, user *journal.User) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.UserService.ReplaceRecoveryCodes(r.Context(), user.ID, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// newRecoveryCodes generates a set of recovery codes along with the hashes
+// to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	return codes, hashes, nil
+}
+
+type totpSetupData struct {
+	Secret string
+	QRCode template.URL
+}
+
+type recoveryCodesData struct {
+	Codes []string
+}
+
+func (s *Server) handleTOTPSetup(w http.ResponseWriter, r *http.Request) {
+	user := journal.UserFromContext(r.Context())
+	if user.HasTOTP() {
+		http.Redirect(w, r, "/settings", http.StatusFound)
+		return
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	issuer := s.Domain
+	if issuer == "" {
+		issuer = r.Host
+	}
+
+	image, err := qrCodeDataURL(totpURI(issuer, user.Email, secret))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// The secret is only saved to the user once they prove they enrolled it
+	session, _ := s.SessionStore.Get(r, sessionCookie)
+	session.Values["totp_secret"] = secret
+	err = session.Save(r, w)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "totpsetup", &totpSetupData{Secret: secret, QRCode: image})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleTOTPEnable(w http.ResponseWriter, r *http.Request) {
+	user := journal.UserFromContext(r.Context())
+
+	err := r.ParseForm()
+	if err != nil {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Failed parsing input"})
+		return
+	}
+
+	session, _ := s.SessionStore.Get(r, sessionCookie)
+	secret, ok := session.Values["totp_secret"].(string)
+	if !ok || secret == "" {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Two-factor setup expired, please start again"})
+		return
+	}
+
+	counter, ok := validateTOTP(secret, r.Form.Get("code"), time.Now())
+	if !ok {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Invalid code, please check your device's clock and try again"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// The code used to enroll can't be used to sign in
+	err = s.UserService.EnableTOTP(r.Context(), user.ID, secret, counter, hashes)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	delete(session.Values, "totp_secret")
+	err = session.Save(r, w)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "recoverycodes", &recoveryCodesData{Codes: codes})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
+
+func (s *Server) handleTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	user := journal.UserFromContext(r.Context())
+
+	err := checkPassword(r, user)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	empty := ""
+	err = s.UserService.UpdateUser(r.Context(), user.ID, &journal.UserUpdate{TOTPSecret: &empty})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = s.UserService.ReplaceRecoveryCodes(r.Context(), user.ID, nil)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings", http.StatusFound)
+}
+
+func (s *Server) handleRecoveryCodesRegenerate(w http.ResponseWriter, r *http.Request) {
+	user := journal.UserFromContext(r.Context())
+	if !user.HasTOTP() {
+		Error(w, r, &journal.Error{Code: journal.EBADINPUT, Message: "Two-factor authentication is not enabled"})
+		return
+	}
+
+	err := checkPassword(r, user)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	codes, err := s.replaceRecoveryCodes(r, user)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	err = render(w, r, "recoverycodes", &recoveryCodesData{Codes: codes})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"testing"
+	"time"
+)
+
+// The SHA-1 test vectors from RFC 6238, Appendix B.
+var rfc6238Key = []byte("12345678901234567890")
+
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+func TestHOTP(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		got := hotp(rfc6238Key, uint64(v.unix/totpPeriod), 8)
+		if got != v.code {
+			t.Errorf("got code %q at %d, expected %q", got, v.unix, v.code)
+		}
+	}
+}
+
+func TestTOTPCode(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfc6238Key)
+	for _, v := range rfc6238Vectors {
+		got, err := totpCode(secret, time.Unix(v.unix, 0))
+		if err != nil {
+			t.Fatalf("failed to compute code: %v", err)
+		}
+		// Same value, truncated to the digits used here
+		expected := v.code[len(v.code)-totpDigits:]
+		if got != expected {
+			t.Errorf("got code %q at %d, expected %q", got, v.unix, expected)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfc6238Key)
+	issued := time.Unix(1111111110, 0) // start of a period
+	code, err := totpCode(secret, issued)
+	if err != nil {
+		t.Fatalf("failed to compute code: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		code  string
+		at    time.Time
+		valid bool
+	}{
+		{"same period", code, issued, true},
+		{"end of period", code, issued.Add(totpPeriod*time.Second - time.Second), true},
+		{"surrounding spaces", " " + code + " ", issued, true},
+		{"previous period", code, issued.Add(-totpSkew * totpPeriod * time.Second), true},
+		{"next period", code, issued.Add(totpSkew * totpPeriod * time.Second), true},
+		{"before skew", code, issued.Add(-(totpSkew + 1) * totpPeriod * time.Second), false},
+		{"after skew", code, issued.Add((totpSkew + 1) * totpPeriod * time.Second), false},
+		{"wrong code", "000000", issued, false},
+		{"too short", code[1:], issued, false},
+		{"empty", "", issued, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			counter, valid := validateTOTP(secret, tc.code, tc.at)
+			if valid != tc.valid {
+				t.Fatalf("got valid %t, expected %t", valid, tc.valid)
+			}
+			// The step of the code, not the one at validation time
+			if expected := issued.Unix() / totpPeriod; valid && counter != expected {
+				t.Errorf("got counter %d, expected %d", counter, expected)
+			}
+		})
+	}
+}
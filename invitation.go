@@ -0,0 +1,35 @@
+package journal
+
+import (
+	"context"
+	"time"
+)
+
+// Invitation lets one person sign up while signup is invite-only. Only the
+// hash of the token is stored, the token itself is shown to the admin once.
+type Invitation struct {
+	ID         int        `json:"id"`
+	TokenHash  string     `json:"-"`
+	CreatedBy  int        `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	ConsumedAt *time.Time `json:"consumedAt"`
+}
+
+// IsValid reports whether the invitation can still be used at time t.
+func (i *Invitation) IsValid(t time.Time) bool {
+	return i.ConsumedAt == nil && i.ExpiresAt.After(t)
+}
+
+type InvitationFilter struct {
+	ID        *int    `json:"id"`
+	TokenHash *string `json:"-"`
+	Offset    int     `json:"offset"`
+	Limit     int     `json:"limit"`
+}
+
+type InvitationService interface {
+	CreateInvitation(ctx context.Context, invitation *Invitation) (err error)
+	DeleteInvitation(ctx context.Context, id int) (err error)
+	FindInvitations(ctx context.Context, filter *InvitationFilter) (invitations []*Invitation, n int, err error)
+}
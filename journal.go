@@ -2,36 +2,106 @@ package journal
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
+const (
+	PostStatusDraft     = "draft"
+	PostStatusPublished = "published"
+	PostStatusUnlisted  = "unlisted"
+)
+
 type Post struct {
-	ID        int       `json:"id"`
-	Permalink string    `json:"permalink"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID          int        `json:"id"`
+	Permalink   string     `json:"permalink"`
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	Status      string     `json:"status"`
+	PublishAt   *time.Time `json:"publishAt"`
+	PublishedAt *time.Time `json:"publishedAt"` // set the first time the post is published
+	Tags        []string   `json:"tags"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// Date returns when the post was published, or when it was created if it
+// never was.
+func (p *Post) Date() time.Time {
+	if p.PublishedAt != nil {
+		return *p.PublishedAt
+	}
+	return p.CreatedAt
+}
+
+func (p *Post) Validate() error {
+	if !IsValidPostStatus(p.Status) {
+		return fmt.Errorf("invalid status %q", p.Status)
+	}
+	return nil
+}
+
+// IsValidPostStatus reports whether status is one of the known post states.
+func IsValidPostStatus(status string) bool {
+	switch status {
+	case PostStatusDraft, PostStatusPublished, PostStatusUnlisted:
+		return true
+	}
+	return false
 }
 
 type PostFilter struct {
 	ID        *int    `json:"id"`
 	Permalink *string `json:"permalink"`
+	Status    *string `json:"status"`
+	Tag       *string `json:"tag"`
 	Offset    int     `json:"offset"`
 	Limit     int     `json:"limit"`
 }
 
+// Tag is a label attached to posts. Count is the number of published posts
+// carrying it.
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
 type PostUpdate struct {
 	Title     *string    `json:"title"`
 	Content   *string    `json:"content"`
+	Status    *string    `json:"status"`
+	PublishAt *time.Time `json:"publishAt"` // zero value clears the schedule
+	Tags      *[]string  `json:"tags"`
 	UpdatedAt *time.Time `json:"updatedAt"`
 }
 
+// PostRevision is a previous version of a post, saved whenever its title or
+// content is updated.
+type PostRevision struct {
+	ID        int       `json:"id"`
+	PostID    int       `json:"postId"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type PostRevisionFilter struct {
+	ID     *int `json:"id"`
+	PostID *int `json:"postId"`
+	Offset int  `json:"offset"`
+	Limit  int  `json:"limit"`
+}
+
 type JournalService interface {
 	CreatePost(ctx context.Context, post *Post) (err error)
 	UpdatePost(ctx context.Context, permalink string, updated *PostUpdate) (err error)
+	DeletePost(ctx context.Context, permalink string) (err error)
+	PublishDuePosts(ctx context.Context) (n int, err error)
 	FindPostByID(ctx context.Context, id int) (post *Post, err error)
-	FindPosts(ctx context.Context) (posts []*Post, err error)
+	FindPosts(ctx context.Context, filter *PostFilter) (posts []*Post, n int, err error)
+	FindTags(ctx context.Context) (tags []*Tag, err error)
+	FindPostRevisions(ctx context.Context, filter *PostRevisionFilter) (revisions []*PostRevision, n int, err error)
+	FindPostRevisionByID(ctx context.Context, id int) (revision *PostRevision, err error)
 	FindPostByPermalink(ctx context.Context, permalink string) (post *Post, err error)
 }
 
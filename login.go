@@ -0,0 +1,43 @@
+package journal
+
+import (
+	"context"
+	"time"
+)
+
+// FailedLogin records a sign in attempt with a wrong email or password. They
+// are used to throttle logins and let admins see who's trying to get in.
+type FailedLogin struct {
+	ID        int       `json:"id"`
+	Email     string    `json:"email"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// FailedLoginCount is how many recent logins as Email failed, and when the
+// latest one did.
+type FailedLoginCount struct {
+	Email  string    `json:"email"`
+	Count  int       `json:"count"`
+	LastAt time.Time `json:"lastAt"`
+}
+
+type FailedLoginFilter struct {
+	Email  *string    `json:"email"`
+	IP     *string    `json:"ip"`
+	Since  *time.Time `json:"since"`
+	Offset int        `json:"offset"`
+	Limit  int        `json:"limit"`
+}
+
+type FailedLoginService interface {
+	CreateFailedLogin(ctx context.Context, login *FailedLogin) (err error)
+	FindFailedLogins(ctx context.Context, filter *FailedLoginFilter) (logins []*FailedLogin, n int, err error)
+	// FindFailedLoginCounts groups the failed logins made after since by
+	// email, keeping the emails with at least min failures.
+	FindFailedLoginCounts(ctx context.Context, since time.Time, min int) (counts []*FailedLoginCount, err error)
+	DeleteFailedLogins(ctx context.Context, email string) (err error)
+	// DeleteFailedLoginsBefore removes the attempts made before t and returns
+	// how many were removed.
+	DeleteFailedLoginsBefore(ctx context.Context, t time.Time) (n int, err error)
+}
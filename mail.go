@@ -0,0 +1,14 @@
+package journal
+
+import "context"
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	SendMail(ctx context.Context, msg *Message) (err error)
+}
@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+	"k8s.io/klog/v2"
+)
+
+var _ journal.Mailer = (*FileMailer)(nil)
+
+// FileMailer is meant for development. It writes each email to a file in
+// dir, or to the log when dir is empty, instead of sending it.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}
+}
+
+func (m *FileMailer) SendMail(ctx context.Context, msg *journal.Message) error {
+	now := time.Now()
+	data := formatMessage(m.from, msg, now)
+
+	if m.dir == "" {
+		klog.Infof("Mail to %s:\n%s", msg.To, data)
+		return nil
+	}
+
+	err := os.MkdirAll(m.dir, 0750)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Join(m.dir, fmt.Sprintf("%d.eml", now.UnixNano()))
+	return os.WriteFile(name, data, 0640)
+}
@@ -0,0 +1,86 @@
+package journal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	MenuItemPage = "page"
+	MenuItemPost = "post"
+	MenuItemTag  = "tag"
+	MenuItemURL  = "url"
+)
+
+// MenuItem is a link in the header navigation. Target is a page name, a
+// post permalink, a tag name or a URL, depending on Type. Items are shown
+// by increasing Position.
+type MenuItem struct {
+	ID        int       `json:"id"`
+	Label     string    `json:"label"`
+	Type      string    `json:"type"`
+	Target    string    `json:"target"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Href returns the address the item links to.
+func (m *MenuItem) Href() string {
+	switch m.Type {
+	case MenuItemPage:
+		return "/p/" + m.Target
+	case MenuItemPost:
+		return "/post/" + m.Target
+	case MenuItemTag:
+		return "/tag/" + m.Target
+	}
+	return m.Target
+}
+
+func (m *MenuItem) Validate() error {
+	if m.Label == "" {
+		return fmt.Errorf("label is required")
+	}
+	if m.Target == "" {
+		return fmt.Errorf("target is required")
+	}
+	switch m.Type {
+	case MenuItemPage, MenuItemPost, MenuItemTag:
+		if strings.Contains(m.Target, "/") {
+			return fmt.Errorf("invalid %s %q", m.Type, m.Target)
+		}
+	case MenuItemURL:
+		if !strings.HasPrefix(m.Target, "/") &&
+			!strings.HasPrefix(m.Target, "http://") &&
+			!strings.HasPrefix(m.Target, "https://") {
+			return fmt.Errorf("invalid URL %q, use a path or an http(s) address", m.Target)
+		}
+	default:
+		return fmt.Errorf("invalid type %q", m.Type)
+	}
+	return nil
+}
+
+type MenuItemFilter struct {
+	ID     *int `json:"id"`
+	Offset int  `json:"offset"`
+	Limit  int  `json:"limit"`
+}
+
+type MenuItemUpdate struct {
+	Label    *string `json:"label"`
+	Position *int    `json:"position"`
+}
+
+type MenuService interface {
+	// CreateMenuItem appends the item to the end of the menu.
+	CreateMenuItem(ctx context.Context, item *MenuItem) (err error)
+	// UpdateMenuItem changes an item. Moving it to a new position shifts the
+	// items in between.
+	UpdateMenuItem(ctx context.Context, id int, updated *MenuItemUpdate) (err error)
+	DeleteMenuItem(ctx context.Context, id int) (err error)
+	FindMenuItems(ctx context.Context, filter *MenuItemFilter) (items []*MenuItem, n int, err error)
+}
@@ -2,17 +2,62 @@ package journal
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
+// Now is an update of the now page. FromLocation is a free text description
+// of where it was written, the other location fields are optional and let
+// it be placed on a map and shown in the author's local time.
 type Now struct {
 	ID           int       `json:"id"`
 	Content      string    `json:"content"`
 	FromLocation string    `json:"from_location"`
+	City         string    `json:"city"`
+	Country      string    `json:"country"`
+	Latitude     *float64  `json:"latitude"`
+	Longitude    *float64  `json:"longitude"`
+	Timezone     string    `json:"timezone"`
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
 }
 
+func (n *Now) Validate() error {
+	if (n.Latitude == nil) != (n.Longitude == nil) {
+		return fmt.Errorf("latitude and longitude must be set together")
+	}
+	if n.Latitude != nil && (*n.Latitude < -90 || *n.Latitude > 90) {
+		return fmt.Errorf("latitude must be between -90 and 90")
+	}
+	if n.Longitude != nil && (*n.Longitude < -180 || *n.Longitude > 180) {
+		return fmt.Errorf("longitude must be between -180 and 180")
+	}
+	if n.Timezone != "" {
+		if _, err := time.LoadLocation(n.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", n.Timezone)
+		}
+	}
+	return nil
+}
+
+// HasCoordinates reports whether the entry can be placed on a map.
+func (n *Now) HasCoordinates() bool {
+	return n.Latitude != nil && n.Longitude != nil
+}
+
+// LocalTime returns t in the timezone of the entry, or false if the entry
+// has no valid timezone.
+func (n *Now) LocalTime(t time.Time) (time.Time, bool) {
+	if n.Timezone == "" {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(n.Timezone)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.In(loc), true
+}
+
 type NowFilter struct {
 	ID     *int `json:"id"`
 	Offset int  `json:"offset"`
@@ -22,4 +67,6 @@ type NowFilter struct {
 type NowService interface {
 	CreateNow(ctx context.Context, now *Now) (err error)
 	FindLatestNow(ctx context.Context) (now *Now, err error)
+	FindNowByID(ctx context.Context, id int) (now *Now, err error)
+	FindNows(ctx context.Context, filter *NowFilter) (nows []*Now, n int, err error)
 }
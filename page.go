@@ -2,17 +2,34 @@ package journal
 
 import (
 	"context"
+	"fmt"
+	"regexp"
 	"time"
 )
 
+// pageNameRegexp matches page names, which are used in URLs.
+var pageNameRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Page is a standalone page, like about or contact, served at /p/{name}.
 type Page struct {
 	ID        int       `json:"id"`
 	Name      string    `json:"name"`
+	Title     string    `json:"title"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+func (p *Page) Validate() error {
+	if !pageNameRegexp.MatchString(p.Name) {
+		return fmt.Errorf("invalid name %q, use lowercase letters, digits and dashes", p.Name)
+	}
+	if p.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	return nil
+}
+
 type PageFilter struct {
 	Name   *string `json:"name"`
 	Offset int     `json:"offset"`
@@ -20,12 +37,34 @@ type PageFilter struct {
 }
 
 type PageUpdate struct {
+	Title     *string    `json:"title"`
 	Content   *string    `json:"content"`
 	UpdatedAt *time.Time `json:"updatedAt"`
 }
 
+// PageRevision is a previous version of a page, saved whenever its title or
+// content is updated.
+type PageRevision struct {
+	ID        int       `json:"id"`
+	PageID    int       `json:"pageId"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type PageRevisionFilter struct {
+	ID     *int `json:"id"`
+	PageID *int `json:"pageId"`
+	Offset int  `json:"offset"`
+	Limit  int  `json:"limit"`
+}
+
 type PageService interface {
 	CreatePage(ctx context.Context, page *Page) (err error)
 	UpdatePage(ctx context.Context, name string, updated *PageUpdate) (err error)
+	DeletePage(ctx context.Context, name string) (err error)
 	FindPageByName(ctx context.Context, name string) (page *Page, err error)
+	FindPages(ctx context.Context, filter *PageFilter) (pages []*Page, n int, err error)
+	FindPageRevisions(ctx context.Context, filter *PageRevisionFilter) (revisions []*PageRevision, n int, err error)
+	FindPageRevisionByID(ctx context.Context, id int) (revision *PageRevision, err error)
 }
@@ -0,0 +1,35 @@
+package journal
+
+import (
+	"context"
+	"time"
+)
+
+// PasswordReset lets a user choose a new password without knowing the
+// current one. Like invitations, only the hash of the token is stored.
+type PasswordReset struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"userId"`
+	TokenHash  string     `json:"-"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	ConsumedAt *time.Time `json:"consumedAt"`
+}
+
+// IsValid reports whether the password reset can still be used at time t.
+func (p *PasswordReset) IsValid(t time.Time) bool {
+	return p.ConsumedAt == nil && p.ExpiresAt.After(t)
+}
+
+type PasswordResetFilter struct {
+	UserID    *int    `json:"userId"`
+	TokenHash *string `json:"-"`
+	Offset    int     `json:"offset"`
+	Limit     int     `json:"limit"`
+}
+
+type PasswordResetService interface {
+	CreatePasswordReset(ctx context.Context, reset *PasswordReset) (err error)
+	FindPasswordResets(ctx context.Context, filter *PasswordResetFilter) (resets []*PasswordReset, n int, err error)
+	ConsumePasswordReset(ctx context.Context, tokenHash string) (reset *PasswordReset, err error)
+}
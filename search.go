@@ -0,0 +1,33 @@
+package journal
+
+import "context"
+
+// Snippets returned by a SearchService wrap each matched term with these
+// markers, so callers can highlight matches after escaping the text.
+const (
+	SearchHighlightStart = "\x02"
+	SearchHighlightEnd   = "\x03"
+)
+
+const (
+	SearchResultPost = "post"
+	SearchResultPage = "page"
+)
+
+type SearchResult struct {
+	Type    string  `json:"type"`
+	Name    string  `json:"name"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+type SearchFilter struct {
+	Query  string `json:"query"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+}
+
+type SearchService interface {
+	Search(ctx context.Context, filter *SearchFilter) (results []*SearchResult, err error)
+}
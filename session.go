@@ -0,0 +1,40 @@
+package journal
+
+import (
+	"context"
+	"time"
+)
+
+// Session is a server-side login session. Data holds the encoded session
+// values and is opaque to everything but the session store.
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    int       `json:"userId"`
+	Data      []byte    `json:"-"`
+	UserAgent string    `json:"userAgent"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type SessionFilter struct {
+	ID     *string `json:"id"`
+	UserID *int    `json:"userId"`
+	Offset int     `json:"offset"`
+	Limit  int     `json:"limit"`
+}
+
+type SessionUpdate struct {
+	UserID    *int       `json:"userId"`
+	Data      *[]byte    `json:"-"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type SessionService interface {
+	CreateSession(ctx context.Context, session *Session) (err error)
+	UpdateSession(ctx context.Context, id string, updated *SessionUpdate) (err error)
+	DeleteSession(ctx context.Context, id string) (err error)
+	FindSessionByID(ctx context.Context, id string) (session *Session, err error)
+	FindSessions(ctx context.Context, filter *SessionFilter) (sessions []*Session, n int, err error)
+	DeleteExpiredSessions(ctx context.Context) (n int, err error)
+}
@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+var _ journal.InvitationService = (*InvitationService)(nil)
+
+type InvitationService struct {
+	db *DB
+}
+
+func NewInvitationService(db *DB) *InvitationService {
+	return &InvitationService{
+		db: db,
+	}
+}
+
+func (s *InvitationService) CreateInvitation(ctx context.Context, invitation *journal.Invitation) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	invitation.CreatedAt = tx.now
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO invitation (
+			token_hash,
+			created_by,
+			created_at,
+			expires_at
+		)
+		VALUES (?,?,?,?)
+	`,
+		invitation.TokenHash,
+		invitation.CreatedBy,
+		invitation.CreatedAt,
+		invitation.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	invitation.ID = int(id)
+
+	return tx.Commit()
+}
+
+func (s *InvitationService) DeleteInvitation(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM invitation WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *InvitationService) FindInvitations(ctx context.Context, filter *journal.InvitationFilter) ([]*journal.Invitation, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findInvitations(ctx, tx, filter)
+}
+
+// consumeInvitation marks the invitation as used. It fails if the invitation
+// doesn't exist, has expired or was already used, so a token can only be
+// consumed once even with concurrent signups.
+func consumeInvitation(ctx context.Context, tx *Tx, tokenHash string) error {
+	result, err := tx.ExecContext(ctx, `
+		UPDATE invitation
+		SET consumed_at = ?
+		WHERE token_hash = ?
+		AND consumed_at IS NULL
+		AND expires_at > ?
+	`,
+		tx.now,
+		tokenHash,
+		tx.now,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Invalid or expired invitation"}
+	}
+
+	return nil
+}
+
+func findInvitations(ctx context.Context, tx *Tx, filter *journal.InvitationFilter) ([]*journal.Invitation, int, error) {
+	// where and args should always be mutate together
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := filter.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := filter.TokenHash; v != nil {
+		where, args = append(where, "token_hash = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+		    id,
+		    token_hash,
+		    created_by,
+		    created_at,
+		    expires_at,
+		    consumed_at,
+		    COUNT(*) OVER()
+		FROM invitation
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		`+formatLimitAndOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var n int
+	invitations := make([]*journal.Invitation, 0)
+	for rows.Next() {
+		var invitation journal.Invitation
+		var consumedAt sql.NullTime
+		if err := rows.Scan(
+			&invitation.ID,
+			&invitation.TokenHash,
+			&invitation.CreatedBy,
+			&invitation.CreatedAt,
+			&invitation.ExpiresAt,
+			&consumedAt,
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		if consumedAt.Valid {
+			invitation.ConsumedAt = &consumedAt.Time
+		}
+		invitations = append(invitations, &invitation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return invitations, n, nil
+}
@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
 
@@ -32,6 +33,15 @@ func (j *JournalService) UpdatePost(ctx context.Context, permalink string, updat
 		return err
 	}
 
+	// Keep the current version around before it gets overwritten
+	if (updated.Title != nil && *updated.Title != post.Title) ||
+		(updated.Content != nil && *updated.Content != post.Content) {
+		err = createPostRevision(ctx, tx, post)
+		if err != nil {
+			return err
+		}
+	}
+
 	if v := updated.Title; v != nil {
 		post.Title = *v
 	}
@@ -40,17 +50,42 @@ func (j *JournalService) UpdatePost(ctx context.Context, permalink string, updat
 		post.Content = *v
 	}
 
+	if v := updated.Status; v != nil {
+		post.Status = *v
+	}
+
+	if post.Status == journal.PostStatusPublished && post.PublishedAt == nil {
+		publishedAt := tx.now
+		post.PublishedAt = &publishedAt
+	}
+
+	if v := updated.PublishAt; v != nil {
+		if v.IsZero() {
+			post.PublishAt = nil
+		} else {
+			// Stored as text, so it must be in UTC to compare with tx.now
+			publishAt := v.UTC()
+			post.PublishAt = &publishAt
+		}
+	}
+
 	post.UpdatedAt = tx.now
 
 	_, err = tx.ExecContext(ctx, `
 		UPDATE posts
         SET title = ?,
 			content = ?,
+			status = ?,
+			publish_at = ?,
+			published_at = ?,
 			updated_at = ?
 		WHERE id = ?
 	`,
 		post.Title,
 		post.Content,
+		post.Status,
+		post.PublishAt,
+		post.PublishedAt,
 		post.UpdatedAt,
 		post.ID,
 	)
@@ -58,10 +93,89 @@ func (j *JournalService) UpdatePost(ctx context.Context, permalink string, updat
 		return err
 	}
 
+	if v := updated.Tags; v != nil {
+		err = replacePostTags(ctx, tx, post.ID, *v)
+		if err != nil {
+			return err
+		}
+	}
+
 	return tx.Commit()
 
 }
 
+func (j *JournalService) DeletePost(ctx context.Context, permalink string) error {
+	tx, err := j.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	post, err := findPostByPermalink(ctx, tx, permalink)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM posts WHERE id = ?`, post.ID)
+	if err != nil {
+		return err
+	}
+
+	err = replacePostTags(ctx, tx, post.ID, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM post_revision WHERE post_id = ?`, post.ID)
+	if err != nil {
+		return err
+	}
+
+	err = deleteMenuItemsTo(ctx, tx, journal.MenuItemPost, post.Permalink)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PublishDuePosts publishes every draft whose publish time has passed and
+// returns how many posts were published.
+func (j *JournalService) PublishDuePosts(ctx context.Context) (int, error) {
+	tx, err := j.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE posts
+		SET status = ?,
+			publish_at = NULL,
+			published_at = COALESCE(published_at, ?),
+			updated_at = ?
+		WHERE status = ?
+		AND publish_at IS NOT NULL
+		AND publish_at <= ?
+	`,
+		journal.PostStatusPublished,
+		tx.now,
+		tx.now,
+		journal.PostStatusDraft,
+		tx.now,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), tx.Commit()
+}
+
 func (j *JournalService) CreatePost(ctx context.Context, post *journal.Post) error {
 	tx, err := j.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -72,19 +186,36 @@ func (j *JournalService) CreatePost(ctx context.Context, post *journal.Post) err
 	post.CreatedAt = tx.now
 	post.UpdatedAt = tx.now
 
+	// Stored as text, so it must be in UTC to compare with tx.now
+	if post.PublishAt != nil {
+		publishAt := post.PublishAt.UTC()
+		post.PublishAt = &publishAt
+	}
+
+	if post.Status == journal.PostStatusPublished {
+		publishedAt := tx.now
+		post.PublishedAt = &publishedAt
+	}
+
 	result, err := tx.ExecContext(ctx, `
 		INSERT INTO posts (
 			permalink,
 			title,
 			content,
+			status,
+			publish_at,
+			published_at,
 			created_at,
 			updated_at
 		)
-		VALUES (?,?,?,?,?)
+		VALUES (?,?,?,?,?,?,?,?)
 	`,
 		post.Permalink,
 		post.Title,
 		post.Content,
+		post.Status,
+		post.PublishAt,
+		post.PublishedAt,
 		post.CreatedAt,
 		post.UpdatedAt,
 	)
@@ -98,6 +229,11 @@ func (j *JournalService) CreatePost(ctx context.Context, post *journal.Post) err
 	}
 	post.ID = int(id)
 
+	err = replacePostTags(ctx, tx, post.ID, post.Tags)
+	if err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -131,23 +267,24 @@ func (j *JournalService) FindPostByPermalink(ctx context.Context, permalink stri
 	return p, err
 }
 
-func (j *JournalService) FindPosts(ctx context.Context) ([]*journal.Post, error) {
+func (j *JournalService) FindPosts(ctx context.Context, filter *journal.PostFilter) ([]*journal.Post, int, error) {
 	tx, err := j.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer tx.Rollback()
 
-	posts, n, err := findPosts(ctx, tx, &journal.PostFilter{})
+	return findPosts(ctx, tx, filter)
+}
+
+func (j *JournalService) FindTags(ctx context.Context) ([]*journal.Tag, error) {
+	tx, err := j.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	if n == 0 {
-		return nil, &journal.Error{Code: journal.ENOTFOUND, Message: "There are no posts available"}
-	}
-
-	return posts, nil
+	return findTags(ctx, tx)
 }
 
 func findPostByPermalink(ctx context.Context, tx *Tx, permalink string) (*journal.Post, error) {
@@ -185,6 +322,12 @@ func findPosts(ctx context.Context, tx *Tx, filter *journal.PostFilter) ([]*jour
 	if v := filter.Permalink; v != nil {
 		where, args = append(where, "permalink = ?"), append(args, *v)
 	}
+	if v := filter.Status; v != nil {
+		where, args = append(where, "status = ?"), append(args, *v)
+	}
+	if v := filter.Tag; v != nil {
+		where, args = append(where, "id IN (SELECT post_tag.post_id FROM post_tag JOIN tag ON tag.id = post_tag.tag_id WHERE tag.name = ?)"), append(args, *v)
+	}
 
 	rows, err := tx.QueryContext(ctx, `
 		SELECT
@@ -192,12 +335,15 @@ func findPosts(ctx context.Context, tx *Tx, filter *journal.PostFilter) ([]*jour
 		    permalink,
 		    title,
             content,
+		    status,
+		    publish_at,
+		    published_at,
 		    created_at,
 		    updated_at,
 		    COUNT(*) OVER()
 		FROM posts
 		WHERE `+strings.Join(where, " AND ")+`
-		ORDER BY id DESC
+		ORDER BY COALESCE(published_at, created_at) DESC, id DESC
 		`+formatLimitAndOffset(filter.Limit, filter.Offset),
 		args...,
 	)
@@ -210,22 +356,37 @@ func findPosts(ctx context.Context, tx *Tx, filter *journal.PostFilter) ([]*jour
 	posts := make([]*journal.Post, 0)
 	for rows.Next() {
 		var post journal.Post
+		var publishAt, publishedAt sql.NullTime
 		if err := rows.Scan(
 			&post.ID,
 			&post.Permalink,
 			&post.Title,
 			&post.Content,
+			&post.Status,
+			&publishAt,
+			&publishedAt,
 			&post.CreatedAt,
 			&post.UpdatedAt,
 			&n,
 		); err != nil {
 			return nil, 0, err
 		}
+		if publishAt.Valid {
+			post.PublishAt = &publishAt.Time
+		}
+		if publishedAt.Valid {
+			post.PublishedAt = &publishedAt.Time
+		}
 		posts = append(posts, &post)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, 0, err
 	}
+	rows.Close()
+
+	if err := attachPostTags(ctx, tx, posts); err != nil {
+		return nil, 0, err
+	}
 
 	return posts, n, nil
 }
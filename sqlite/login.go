@@ -0,0 +1,206 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+var _ journal.FailedLoginService = (*FailedLoginService)(nil)
+
+type FailedLoginService struct {
+	db *DB
+}
+
+func NewFailedLoginService(db *DB) *FailedLoginService {
+	return &FailedLoginService{
+		db: db,
+	}
+}
+
+func (s *FailedLoginService) CreateFailedLogin(ctx context.Context, login *journal.FailedLogin) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	login.CreatedAt = tx.now
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO failed_login (
+			email,
+			ip,
+			created_at
+		)
+		VALUES (?,?,?)
+	`,
+		login.Email,
+		login.IP,
+		login.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	login.ID = int(id)
+
+	return tx.Commit()
+}
+
+// DeleteFailedLogins forgets every failed attempt for email, which unlocks
+// the account.
+func (s *FailedLoginService) DeleteFailedLogins(ctx context.Context, email string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM failed_login WHERE email = ?`, email)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *FailedLoginService) DeleteFailedLoginsBefore(ctx context.Context, t time.Time) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM failed_login WHERE created_at < ?`, t.UTC().Truncate(time.Second))
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), tx.Commit()
+}
+
+func (s *FailedLoginService) FindFailedLogins(ctx context.Context, filter *journal.FailedLoginFilter) ([]*journal.FailedLogin, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findFailedLogins(ctx, tx, filter)
+}
+
+func (s *FailedLoginService) FindFailedLoginCounts(ctx context.Context, since time.Time, min int) ([]*journal.FailedLoginCount, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+		    email,
+		    COUNT(*),
+		    MAX(created_at)
+		FROM failed_login
+		WHERE created_at > ?
+		GROUP BY email
+		HAVING COUNT(*) >= ?
+		ORDER BY email
+	`,
+		since.UTC().Truncate(time.Second),
+		min,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]*journal.FailedLoginCount, 0)
+	for rows.Next() {
+		var count journal.FailedLoginCount
+		var lastAt string
+		if err := rows.Scan(
+			&count.Email,
+			&count.Count,
+			&lastAt,
+		); err != nil {
+			return nil, err
+		}
+		count.LastAt, err = parseTime(lastAt)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, &count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func findFailedLogins(ctx context.Context, tx *Tx, filter *journal.FailedLoginFilter) ([]*journal.FailedLogin, int, error) {
+	// where and args should always be mutate together
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := filter.Email; v != nil {
+		where, args = append(where, "email = ?"), append(args, *v)
+	}
+	if v := filter.IP; v != nil {
+		where, args = append(where, "ip = ?"), append(args, *v)
+	}
+	if v := filter.Since; v != nil {
+		// Stored times have no fractional seconds, keep the comparison consistent
+		where, args = append(where, "created_at > ?"), append(args, v.UTC().Truncate(time.Second))
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+		    id,
+		    email,
+		    ip,
+		    created_at,
+		    COUNT(*) OVER()
+		FROM failed_login
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		`+formatLimitAndOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var n int
+	logins := make([]*journal.FailedLogin, 0)
+	for rows.Next() {
+		var login journal.FailedLogin
+		if err := rows.Scan(
+			&login.ID,
+			&login.Email,
+			&login.IP,
+			&login.CreatedAt,
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		logins = append(logins, &login)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return logins, n, nil
+}
@@ -0,0 +1,270 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+var _ journal.MenuService = (*MenuService)(nil)
+
+type MenuService struct {
+	db *DB
+}
+
+func NewMenuService(db *DB) *MenuService {
+	return &MenuService{
+		db: db,
+	}
+}
+
+func (m *MenuService) CreateMenuItem(ctx context.Context, item *journal.MenuItem) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, n, err := findMenuItems(ctx, tx, &journal.MenuItemFilter{})
+	if err != nil {
+		return err
+	}
+
+	item.Position = n
+	item.CreatedAt = tx.now
+	item.UpdatedAt = tx.now
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO menu_item (
+			label,
+			type,
+			target,
+			position,
+			created_at,
+			updated_at
+		)
+		VALUES (?,?,?,?,?,?)
+	`,
+		item.Label,
+		item.Type,
+		item.Target,
+		item.Position,
+		item.CreatedAt,
+		item.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	item.ID = int(id)
+
+	return tx.Commit()
+}
+
+func (m *MenuService) UpdateMenuItem(ctx context.Context, id int, updated *journal.MenuItemUpdate) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	item, err := findMenuItemByID(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if v := updated.Label; v != nil {
+		item.Label = *v
+	}
+
+	item.UpdatedAt = tx.now
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE menu_item
+		SET label = ?,
+			updated_at = ?
+		WHERE id = ?
+	`,
+		item.Label,
+		item.UpdatedAt,
+		item.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if v := updated.Position; v != nil {
+		err = moveMenuItem(ctx, tx, item.ID, *v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *MenuService) DeleteMenuItem(ctx context.Context, id int) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	item, err := findMenuItemByID(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM menu_item WHERE id = ?`, item.ID)
+	if err != nil {
+		return err
+	}
+
+	err = moveMenuItem(ctx, tx, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *MenuService) FindMenuItems(ctx context.Context, filter *journal.MenuItemFilter) ([]*journal.MenuItem, int, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findMenuItems(ctx, tx, filter)
+}
+
+// deleteMenuItemsTo removes the items linking to content that is going away,
+// like a deleted page or post.
+func deleteMenuItemsTo(ctx context.Context, tx *Tx, typ, target string) error {
+	result, err := tx.ExecContext(ctx, `DELETE FROM menu_item WHERE type = ? AND target = ?`, typ, target)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	return moveMenuItem(ctx, tx, 0, 0)
+}
+
+// moveMenuItem moves the item with the given ID to position, clamped to the
+// menu bounds, and numbers every item from zero again so positions stay
+// contiguous. An ID of zero only renumbers the items.
+func moveMenuItem(ctx context.Context, tx *Tx, id, position int) error {
+	items, _, err := findMenuItems(ctx, tx, &journal.MenuItemFilter{})
+	if err != nil {
+		return err
+	}
+
+	ordered := make([]*journal.MenuItem, 0, len(items))
+	var moved *journal.MenuItem
+	for _, item := range items {
+		if item.ID == id {
+			moved = item
+			continue
+		}
+		ordered = append(ordered, item)
+	}
+
+	if moved != nil {
+		if position < 0 {
+			position = 0
+		} else if position > len(ordered) {
+			position = len(ordered)
+		}
+		ordered = append(ordered[:position], append([]*journal.MenuItem{moved}, ordered[position:]...)...)
+	}
+
+	for i, item := range ordered {
+		if item.Position == i {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE menu_item SET position = ? WHERE id = ?`, i, item.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func findMenuItemByID(ctx context.Context, tx *Tx, id int) (*journal.MenuItem, error) {
+	items, n, err := findMenuItems(ctx, tx, &journal.MenuItemFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, &journal.Error{Code: journal.ENOTFOUND, Message: "Menu item not found"}
+	}
+
+	return items[0], nil
+}
+
+func findMenuItems(ctx context.Context, tx *Tx, filter *journal.MenuItemFilter) ([]*journal.MenuItem, int, error) {
+	// where and args should always be mutate together
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := filter.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+		    id,
+		    label,
+		    type,
+		    target,
+		    position,
+		    created_at,
+		    updated_at,
+		    COUNT(*) OVER()
+		FROM menu_item
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY position ASC, id ASC
+		`+formatLimitAndOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var n int
+	items := make([]*journal.MenuItem, 0)
+	for rows.Next() {
+		var item journal.MenuItem
+		if err := rows.Scan(
+			&item.ID,
+			&item.Label,
+			&item.Type,
+			&item.Target,
+			&item.Position,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return items, n, nil
+}
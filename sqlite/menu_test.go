@@ -0,0 +1,150 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+// menuLabels returns the labels of the menu, in order.
+func menuLabels(t *testing.T, s *MenuService) []string {
+	t.Helper()
+
+	items, _, err := s.FindMenuItems(context.Background(), &journal.MenuItemFilter{})
+	if err != nil {
+		t.Fatalf("failed to find menu items: %v", err)
+	}
+	labels := make([]string, 0, len(items))
+	for _, item := range items {
+		labels = append(labels, item.Label+" "+item.Href())
+	}
+	return labels
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMigrateMenu(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	conn := mustMigrateTo(t, path, "0000000015")
+
+	for _, page := range []struct {
+		name  string
+		inNav bool
+	}{
+		{"about", true},
+		{"uses", true},
+		{"colophon", false},
+	} {
+		_, err := conn.Exec(`INSERT INTO page (name, title, content, in_nav, created_at, updated_at) VALUES (?, ?, 'Content', ?, datetime(), datetime())`, page.name, page.name, page.inNav)
+		if err != nil {
+			t.Fatalf("failed to insert page: %v", err)
+		}
+	}
+	conn.Close()
+
+	db := NewDB(path)
+	err := db.Open()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// The old header links come first, even for pages never written
+	expected := []string{"Contact /p/contact", "Now /now", "About /p/about", "uses /p/uses"}
+	if labels := menuLabels(t, NewMenuService(db)); !equalStrings(labels, expected) {
+		t.Errorf("got menu %q, expected %q", labels, expected)
+	}
+
+	_, err = db.db.Exec(`SELECT in_nav FROM page`)
+	if err == nil {
+		t.Error("page.in_nav was not dropped")
+	}
+
+	// Pages are still searchable after the table was rebuilt
+	err = NewPageService(db).CreatePage(context.Background(), &journal.Page{Name: "garden", Title: "Garden", Content: "Tomatoes"})
+	if err != nil {
+		t.Fatalf("failed to create page: %v", err)
+	}
+	results, err := NewSearchService(db).Search(context.Background(), &journal.SearchFilter{Query: "tomatoes"})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "garden" {
+		t.Errorf("got %d search results, expected page/garden", len(results))
+	}
+}
+
+func TestUpdateMenuItemPosition(t *testing.T) {
+	db := mustOpenDB(t)
+	ctx := context.Background()
+	s := NewMenuService(db)
+
+	// Start from an empty menu
+	items, _, err := s.FindMenuItems(ctx, &journal.MenuItemFilter{})
+	if err != nil {
+		t.Fatalf("failed to find menu items: %v", err)
+	}
+	for _, item := range items {
+		if err := s.DeleteMenuItem(ctx, item.ID); err != nil {
+			t.Fatalf("failed to delete menu item: %v", err)
+		}
+	}
+
+	ids := make(map[string]int)
+	for _, label := range []string{"A", "B", "C", "D"} {
+		item := &journal.MenuItem{Label: label, Type: journal.MenuItemURL, Target: "/" + label}
+		if err := s.CreateMenuItem(ctx, item); err != nil {
+			t.Fatalf("failed to create menu item: %v", err)
+		}
+		ids[label] = item.ID
+	}
+
+	for _, tc := range []struct {
+		label    string
+		position int
+		expected []string
+	}{
+		{"D", 0, []string{"D", "A", "B", "C"}},
+		{"D", 3, []string{"A", "B", "C", "D"}},
+		{"A", 2, []string{"B", "C", "A", "D"}},
+	} {
+		position := tc.position
+		err := s.UpdateMenuItem(ctx, ids[tc.label], &journal.MenuItemUpdate{Position: &position})
+		if err != nil {
+			t.Fatalf("failed to move menu item: %v", err)
+		}
+		expected := make([]string, 0, len(tc.expected))
+		for _, label := range tc.expected {
+			expected = append(expected, label+" /"+label)
+		}
+		if labels := menuLabels(t, s); !equalStrings(labels, expected) {
+			t.Errorf("got menu %q after moving %s to %d, expected %q", labels, tc.label, tc.position, expected)
+		}
+	}
+
+	// Deleting an item closes the gap
+	err = s.DeleteMenuItem(ctx, ids["C"])
+	if err != nil {
+		t.Fatalf("failed to delete menu item: %v", err)
+	}
+	position := 2
+	err = s.UpdateMenuItem(ctx, ids["B"], &journal.MenuItemUpdate{Position: &position})
+	if err != nil {
+		t.Fatalf("failed to move menu item: %v", err)
+	}
+	if labels, expected := menuLabels(t, s), []string{"A /A", "D /D", "B /B"}; !equalStrings(labels, expected) {
+		t.Errorf("got menu %q, expected %q", labels, expected)
+	}
+}
@@ -0,0 +1,2 @@
+ALTER TABLE posts
+ADD COLUMN status TEXT NOT NULL DEFAULT 'published';
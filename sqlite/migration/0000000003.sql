@@ -0,0 +1,2 @@
+ALTER TABLE posts
+ADD COLUMN publish_at TIMESTAMP;
@@ -0,0 +1,48 @@
+-- requires: fts5
+-- Full-text search indexes. These require SQLite to be built with FTS5
+-- support, e.g. go build -tags sqlite_fts5.
+CREATE VIRTUAL TABLE IF NOT EXISTS post_fts USING fts5 (
+    title,
+    content,
+    content = 'posts',
+    content_rowid = 'id',
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO post_fts (rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_delete AFTER DELETE ON posts BEGIN
+    INSERT INTO post_fts (post_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_update AFTER UPDATE OF title, content ON posts BEGIN
+    INSERT INTO post_fts (post_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+    INSERT INTO post_fts (rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+INSERT INTO post_fts (post_fts) VALUES ('rebuild');
+
+CREATE VIRTUAL TABLE IF NOT EXISTS page_fts USING fts5 (
+    name,
+    content,
+    content = 'page',
+    content_rowid = 'id',
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS page_fts_insert AFTER INSERT ON page BEGIN
+    INSERT INTO page_fts (rowid, name, content) VALUES (new.id, new.name, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS page_fts_delete AFTER DELETE ON page BEGIN
+    INSERT INTO page_fts (page_fts, rowid, name, content) VALUES ('delete', old.id, old.name, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS page_fts_update AFTER UPDATE OF name, content ON page BEGIN
+    INSERT INTO page_fts (page_fts, rowid, name, content) VALUES ('delete', old.id, old.name, old.content);
+    INSERT INTO page_fts (rowid, name, content) VALUES (new.id, new.name, new.content);
+END;
+
+INSERT INTO page_fts (page_fts) VALUES ('rebuild');
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS post_revision (
+    id INTEGER PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS post_revision_post_id_idx ON post_revision (post_id);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS user_api_key_idx ON user (api_key);
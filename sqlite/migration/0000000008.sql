@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS session (
+    id TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    data BLOB NOT NULL,
+    user_agent TEXT NOT NULL,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS session_user_id_idx ON session (user_id);
+CREATE INDEX IF NOT EXISTS session_expires_at_idx ON session (expires_at);
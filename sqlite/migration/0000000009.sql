@@ -0,0 +1,7 @@
+ALTER TABLE user
+ADD COLUMN role TEXT NOT NULL DEFAULT 'reader';
+
+-- Every account could edit the site before roles existed, but only the
+-- owner, the first account, gets to administer it
+UPDATE user SET role = 'editor';
+UPDATE user SET role = 'admin' WHERE id = (SELECT MIN(id) FROM user);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS failed_login (
+    id INTEGER PRIMARY KEY,
+    email TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    created_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS failed_login_email_idx ON failed_login (email, created_at);
+CREATE INDEX IF NOT EXISTS failed_login_ip_idx ON failed_login (ip, created_at);
@@ -0,0 +1,11 @@
+ALTER TABLE user
+ADD COLUMN totp_secret TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS recovery_code (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES user (id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    created_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS recovery_code_user_id_idx ON recovery_code (user_id);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS password_reset (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES user (id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS password_reset_user_id_idx ON password_reset (user_id);
@@ -0,0 +1,16 @@
+-- Only the latest page with each name was ever shown, drop the others so
+-- names can be unique
+DELETE FROM page WHERE id NOT IN (SELECT MAX(id) FROM page GROUP BY name);
+
+CREATE UNIQUE INDEX IF NOT EXISTS page_name_idx ON page (name);
+
+ALTER TABLE page
+ADD COLUMN title TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE page
+ADD COLUMN in_nav BOOLEAN NOT NULL DEFAULT 0;
+
+UPDATE page SET title = upper(substr(name, 1, 1)) || substr(name, 2);
+
+-- The header used to link to these pages
+UPDATE page SET in_nav = 1 WHERE name IN ('about', 'contact');
@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS menu_item (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    label TEXT NOT NULL,
+    type TEXT NOT NULL,
+    target TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS menu_item_position_idx ON menu_item (position);
+
+-- Start with the links the header used to have, whether or not their pages
+-- were written yet, followed by any other page that was in the header
+INSERT INTO menu_item (label, type, target, position, created_at, updated_at)
+VALUES
+    ('Contact', 'page', 'contact', 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+    ('Now', 'url', '/now', 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+    ('About', 'page', 'about', 2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+INSERT INTO menu_item (label, type, target, position, created_at, updated_at)
+SELECT title, 'page', name, 2 + ROW_NUMBER() OVER (ORDER BY name), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+FROM page
+WHERE in_nav = 1 AND name NOT IN ('about', 'contact');
+
+-- The menu supersedes page.in_nav. This SQLite can't drop columns, so the
+-- table is rebuilt without it, which drops its search triggers too; they
+-- are recreated by a later migration, as they need FTS5.
+CREATE TEMP TABLE page_backup AS
+SELECT id, name, content, created_at, updated_at, title FROM page;
+
+DROP TABLE page;
+
+CREATE TABLE page (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    title TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO page (id, name, content, created_at, updated_at, title)
+SELECT id, name, content, created_at, updated_at, title FROM page_backup;
+
+DROP TABLE page_backup;
+
+CREATE UNIQUE INDEX IF NOT EXISTS page_name_idx ON page (name);
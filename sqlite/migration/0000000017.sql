@@ -0,0 +1,16 @@
+-- requires: fts5
+-- Migration 16 rebuilt the page table, which dropped these triggers.
+CREATE TRIGGER IF NOT EXISTS page_fts_insert AFTER INSERT ON page BEGIN
+    INSERT INTO page_fts (rowid, name, content) VALUES (new.id, new.name, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS page_fts_delete AFTER DELETE ON page BEGIN
+    INSERT INTO page_fts (page_fts, rowid, name, content) VALUES ('delete', old.id, old.name, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS page_fts_update AFTER UPDATE OF name, content ON page BEGIN
+    INSERT INTO page_fts (page_fts, rowid, name, content) VALUES ('delete', old.id, old.name, old.content);
+    INSERT INTO page_fts (rowid, name, content) VALUES (new.id, new.name, new.content);
+END;
+
+INSERT INTO page_fts (page_fts) VALUES ('rebuild');
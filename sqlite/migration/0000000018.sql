@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS page_revision (
+    id INTEGER PRIMARY KEY,
+    page_id INTEGER NOT NULL REFERENCES page (id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS page_revision_page_id_idx ON page_revision (page_id);
+
+-- The about table predates the page table and was never read. Keep anything
+-- it holds as history of the about page before dropping it.
+INSERT INTO page_revision (page_id, title, content, created_at)
+SELECT page.id, page.title, about.content, COALESCE(about.updated_at, about.created_at, page.created_at)
+FROM about
+JOIN page ON page.name = 'about'
+ORDER BY about.id;
+
+DROP TABLE IF EXISTS about;
@@ -0,0 +1,14 @@
+ALTER TABLE now
+ADD COLUMN city TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE now
+ADD COLUMN country TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE now
+ADD COLUMN latitude REAL;
+
+ALTER TABLE now
+ADD COLUMN longitude REAL;
+
+ALTER TABLE now
+ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
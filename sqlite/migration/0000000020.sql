@@ -0,0 +1,3 @@
+-- The TOTP counter of the last code used to sign in, so it can't be replayed
+ALTER TABLE user
+ADD COLUMN totp_counter INTEGER NOT NULL DEFAULT 0;
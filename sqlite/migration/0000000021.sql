@@ -0,0 +1,6 @@
+-- When a post first went public, so scheduled posts sort and show by the
+-- time they were published rather than when the draft was started
+ALTER TABLE posts
+ADD COLUMN published_at TIMESTAMP;
+
+UPDATE posts SET published_at = created_at WHERE status = 'published';
@@ -0,0 +1,11 @@
+-- Emails are now stored in lower case. Addresses that only differ in case
+-- from another account are left alone for an admin to sort out, since
+-- email is unique.
+UPDATE user
+SET email = lower(email)
+WHERE email <> lower(email)
+AND NOT EXISTS (
+    SELECT 1 FROM user AS other
+    WHERE other.id <> user.id
+    AND lower(other.email) = lower(user.email)
+);
@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
 	"strings"
 
 	journal "github.com/bertinatto/journal3"
@@ -32,14 +33,24 @@ func (j *NowService) CreateNow(ctx context.Context, now *journal.Now) error {
 	result, err := tx.ExecContext(ctx, `
 		INSERT INTO now (
 			content,
-            location,
+			location,
+			city,
+			country,
+			latitude,
+			longitude,
+			timezone,
 			created_at,
 			updated_at
 		)
-		VALUES (?,?,?,?)
+		VALUES (?,?,?,?,?,?,?,?,?)
 	`,
 		now.Content,
 		now.FromLocation,
+		now.City,
+		now.Country,
+		now.Latitude,
+		now.Longitude,
+		now.Timezone,
 		now.CreatedAt,
 		now.UpdatedAt,
 	)
@@ -72,6 +83,39 @@ func (j *NowService) FindLatestNow(ctx context.Context) (*journal.Now, error) {
 
 }
 
+func (j *NowService) FindNowByID(ctx context.Context, id int) (*journal.Now, error) {
+	tx, err := j.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return findNowByID(ctx, tx, id)
+}
+
+func (j *NowService) FindNows(ctx context.Context, filter *journal.NowFilter) ([]*journal.Now, int, error) {
+	tx, err := j.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findNows(ctx, tx, filter)
+}
+
+func findNowByID(ctx context.Context, tx *Tx, id int) (*journal.Now, error) {
+	nows, n, err := findNows(ctx, tx, &journal.NowFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, &journal.Error{Code: journal.ENOTFOUND, Message: "Now content not found"}
+	}
+
+	return nows[0], nil
+}
+
 func findLatestNow(ctx context.Context, tx *Tx) (*journal.Now, error) {
 	nows, n, err := findNows(ctx, tx, &journal.NowFilter{Limit: 1, Offset: 0})
 	if err != nil {
@@ -95,8 +139,13 @@ func findNows(ctx context.Context, tx *Tx, filter *journal.NowFilter) ([]*journa
 	rows, err := tx.QueryContext(ctx, `
 		SELECT
 		    id,
-            content,
-            location,
+		    content,
+		    location,
+		    city,
+		    country,
+		    latitude,
+		    longitude,
+		    timezone,
 		    created_at,
 		    updated_at,
 		    COUNT(*) OVER()
@@ -115,16 +164,26 @@ func findNows(ctx context.Context, tx *Tx, filter *journal.NowFilter) ([]*journa
 	nows := make([]*journal.Now, 0)
 	for rows.Next() {
 		var now journal.Now
+		var latitude, longitude sql.NullFloat64
 		if err := rows.Scan(
 			&now.ID,
 			&now.Content,
 			&now.FromLocation,
+			&now.City,
+			&now.Country,
+			&latitude,
+			&longitude,
+			&now.Timezone,
 			&now.CreatedAt,
 			&now.UpdatedAt,
 			&n,
 		); err != nil {
 			return nil, 0, err
 		}
+		if latitude.Valid && longitude.Valid {
+			now.Latitude = &latitude.Float64
+			now.Longitude = &longitude.Float64
+		}
 		nows = append(nows, &now)
 	}
 	if err := rows.Err(); err != nil {
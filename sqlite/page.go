@@ -28,19 +28,28 @@ func (p *PageService) CreatePage(ctx context.Context, page *journal.Page) error
 	}
 	defer tx.Rollback()
 
+	_, err = findPageByName(ctx, tx, page.Name)
+	if err == nil {
+		return &journal.Error{Code: journal.EBADINPUT, Message: "Page already exists"}
+	} else if journal.ErrorCode(err) != journal.ENOTFOUND {
+		return err
+	}
+
 	page.CreatedAt = tx.now
 	page.UpdatedAt = tx.now
 
 	result, err := tx.ExecContext(ctx, `
 		INSERT INTO page (
 			name,
+			title,
 			content,
 			created_at,
 			updated_at
 		)
-		VALUES (?,?,?,?)
+		VALUES (?,?,?,?,?)
 	`,
 		page.Name,
+		page.Title,
 		page.Content,
 		page.CreatedAt,
 		page.UpdatedAt,
@@ -71,6 +80,19 @@ func (p *PageService) UpdatePage(ctx context.Context, name string, updated *jour
 		return err
 	}
 
+	// Keep the current version around before it gets overwritten
+	if (updated.Title != nil && *updated.Title != page.Title) ||
+		(updated.Content != nil && *updated.Content != page.Content) {
+		err = createPageRevision(ctx, tx, page)
+		if err != nil {
+			return err
+		}
+	}
+
+	if v := updated.Title; v != nil {
+		page.Title = *v
+	}
+
 	if v := updated.Content; v != nil {
 		page.Content = *v
 	}
@@ -79,10 +101,12 @@ func (p *PageService) UpdatePage(ctx context.Context, name string, updated *jour
 
 	_, err = tx.ExecContext(ctx, `
 		UPDATE page
-        SET content = ?,
+        SET title = ?,
+			content = ?,
 			updated_at = ?
 		WHERE id = ?
 	`,
+		page.Title,
 		page.Content,
 		page.UpdatedAt,
 		page.ID,
@@ -94,6 +118,36 @@ func (p *PageService) UpdatePage(ctx context.Context, name string, updated *jour
 	return tx.Commit()
 }
 
+func (p *PageService) DeletePage(ctx context.Context, name string) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	page, err := findPageByName(ctx, tx, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM page WHERE id = ?`, page.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM page_revision WHERE page_id = ?`, page.ID)
+	if err != nil {
+		return err
+	}
+
+	err = deleteMenuItemsTo(ctx, tx, journal.MenuItemPage, page.Name)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (p *PageService) FindPageByName(ctx context.Context, name string) (*journal.Page, error) {
 	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -109,6 +163,16 @@ func (p *PageService) FindPageByName(ctx context.Context, name string) (*journal
 	return page, err
 }
 
+func (p *PageService) FindPages(ctx context.Context, filter *journal.PageFilter) ([]*journal.Page, int, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findPages(ctx, tx, filter)
+}
+
 func findPageByName(ctx context.Context, tx *Tx, name string) (*journal.Page, error) {
 	pages, n, err := findPages(ctx, tx, &journal.PageFilter{Name: &name})
 	if err != nil {
@@ -133,13 +197,14 @@ func findPages(ctx context.Context, tx *Tx, filter *journal.PageFilter) ([]*jour
 		SELECT
 		    id,
 		    name,
-            content,
+		    title,
+		    content,
 		    created_at,
 		    updated_at,
 		    COUNT(*) OVER()
 		FROM page
 		WHERE `+strings.Join(where, " AND ")+`
-		ORDER BY id DESC
+		ORDER BY name ASC
 		`+formatLimitAndOffset(filter.Limit, filter.Offset),
 		args...,
 	)
@@ -155,6 +220,7 @@ func findPages(ctx context.Context, tx *Tx, filter *journal.PageFilter) ([]*jour
 		if err := rows.Scan(
 			&page.ID,
 			&page.Name,
+			&page.Title,
 			&page.Content,
 			&page.CreatedAt,
 			&page.UpdatedAt,
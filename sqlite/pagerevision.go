@@ -0,0 +1,55 @@
+package sqlite
+
+import (
+	"context"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+func (p *PageService) FindPageRevisions(ctx context.Context, filter *journal.PageRevisionFilter) ([]*journal.PageRevision, int, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findPageRevisions(ctx, tx, filter)
+}
+
+func (p *PageService) FindPageRevisionByID(ctx context.Context, id int) (*journal.PageRevision, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	revisions, n, err := findPageRevisions(ctx, tx, &journal.PageRevisionFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, &journal.Error{Code: journal.ENOTFOUND, Message: "Revision not found"}
+	}
+
+	return revisions[0], nil
+}
+
+// createPageRevision saves the current title and content of page.
+func createPageRevision(ctx context.Context, tx *Tx, page *journal.Page) error {
+	return createRevision(ctx, tx, pageRevisionTable, page.ID, page.Title, page.Content, page.UpdatedAt)
+}
+
+func findPageRevisions(ctx context.Context, tx *Tx, filter *journal.PageRevisionFilter) ([]*journal.PageRevision, int, error) {
+	revisions := make([]*journal.PageRevision, 0)
+	n, err := findRevisions(ctx, tx, pageRevisionTable, filter.ID, filter.PageID, filter.Limit, filter.Offset, func() []interface{} {
+		var revision journal.PageRevision
+		revisions = append(revisions, &revision)
+		return []interface{}{&revision.ID, &revision.PageID, &revision.Title, &revision.Content, &revision.CreatedAt}
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return revisions, n, nil
+}
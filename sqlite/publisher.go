@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const defaultPublishInterval = time.Minute
+
+// Publisher periodically publishes scheduled drafts once their PublishAt
+// time has passed.
+type Publisher struct {
+	service *JournalService
+
+	Interval time.Duration
+}
+
+func NewPublisher(db *DB) *Publisher {
+	return &Publisher{
+		service:  NewJournalService(db),
+		Interval: defaultPublishInterval,
+	}
+}
+
+// Run polls for due posts until ctx is cancelled.
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.publish(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Publisher) publish(ctx context.Context) {
+	n, err := p.service.PublishDuePosts(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			klog.Errorf("Failed to publish scheduled posts: %v", err)
+		}
+		return
+	}
+	if n > 0 {
+		klog.Infof("Published %d scheduled post(s)", n)
+	}
+}
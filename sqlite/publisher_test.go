@@ -0,0 +1,191 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+func TestPublishDuePosts(t *testing.T) {
+	db := mustOpenDB(t)
+	ctx := context.Background()
+
+	now := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	db.Now = func() time.Time { return now }
+
+	s := NewJournalService(db)
+	publishAt := now.Add(time.Hour)
+	err := s.CreatePost(ctx, &journal.Post{
+		Permalink: "scheduled",
+		Title:     "Scheduled",
+		Content:   "Content",
+		Status:    journal.PostStatusDraft,
+		PublishAt: &publishAt,
+	})
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	n, err := s.PublishDuePosts(ctx)
+	if err != nil {
+		t.Fatalf("failed to publish posts: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("published %d post(s) before publish_at, expected 0", n)
+	}
+
+	now = publishAt.Add(time.Second)
+	n, err = s.PublishDuePosts(ctx)
+	if err != nil {
+		t.Fatalf("failed to publish posts: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("published %d post(s) after publish_at, expected 1", n)
+	}
+
+	post, err := s.FindPostByPermalink(ctx, "scheduled")
+	if err != nil {
+		t.Fatalf("failed to find post: %v", err)
+	}
+	if post.Status != journal.PostStatusPublished {
+		t.Errorf("got status %q, expected %q", post.Status, journal.PostStatusPublished)
+	}
+	if post.PublishAt != nil {
+		t.Errorf("got publish_at %v, expected it to be cleared", post.PublishAt)
+	}
+	if !post.UpdatedAt.Equal(now) {
+		t.Errorf("got updated_at %v, expected %v", post.UpdatedAt, now)
+	}
+	if post.PublishedAt == nil || !post.PublishedAt.Equal(now) {
+		t.Errorf("got published_at %v, expected %v", post.PublishedAt, now)
+	}
+}
+
+func TestPublishedPostsOrder(t *testing.T) {
+	db := mustOpenDB(t)
+	ctx := context.Background()
+
+	now := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	db.Now = func() time.Time { return now }
+
+	// The scheduled draft is started first but goes out last
+	s := NewJournalService(db)
+	published := now.Add(time.Hour)
+	publishAt := now.Add(2 * time.Hour)
+	for _, post := range []*journal.Post{
+		{Permalink: "scheduled", Title: "Scheduled", Content: "Content", Status: journal.PostStatusDraft, PublishAt: &publishAt},
+		{Permalink: "published", Title: "Published", Content: "Content", Status: journal.PostStatusPublished},
+	} {
+		err := s.CreatePost(ctx, post)
+		if err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+		now = now.Add(time.Hour)
+	}
+
+	now = publishAt
+	_, err := s.PublishDuePosts(ctx)
+	if err != nil {
+		t.Fatalf("failed to publish posts: %v", err)
+	}
+
+	// Republishing keeps the original date
+	now = now.Add(time.Hour)
+	for _, status := range []string{journal.PostStatusDraft, journal.PostStatusPublished} {
+		status := status
+		err = s.UpdatePost(ctx, "published", &journal.PostUpdate{Status: &status})
+		if err != nil {
+			t.Fatalf("failed to update post: %v", err)
+		}
+	}
+
+	posts, _, err := s.FindPosts(ctx, &journal.PostFilter{})
+	if err != nil {
+		t.Fatalf("failed to find posts: %v", err)
+	}
+	var permalinks []string
+	for _, post := range posts {
+		permalinks = append(permalinks, post.Permalink)
+	}
+	if len(permalinks) != 2 || permalinks[0] != "scheduled" || permalinks[1] != "published" {
+		t.Errorf("got posts %v, expected the scheduled post first", permalinks)
+	}
+	if !posts[1].Date().Equal(published) {
+		t.Errorf("got date %v for the republished post, expected %v", posts[1].Date(), published)
+	}
+}
+
+func TestPublishDuePostsTimeZones(t *testing.T) {
+	db := mustOpenDB(t)
+	ctx := context.Background()
+
+	now := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	db.Now = func() time.Time { return now }
+
+	// Both are due at 13:00 UTC, but would compare as 10:00 and 18:00 if
+	// stored with their offsets
+	due := now.Add(time.Hour)
+	west := due.In(time.FixedZone("UTC-3", -3*60*60))
+	east := due.In(time.FixedZone("UTC+5", 5*60*60))
+
+	s := NewJournalService(db)
+	for _, permalink := range []string{"created", "updated"} {
+		post := &journal.Post{
+			Permalink: permalink,
+			Title:     "Scheduled",
+			Content:   "Content",
+			Status:    journal.PostStatusDraft,
+		}
+		if permalink == "created" {
+			post.PublishAt = &west
+		}
+		err := s.CreatePost(ctx, post)
+		if err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+	}
+	err := s.UpdatePost(ctx, "updated", &journal.PostUpdate{PublishAt: &east})
+	if err != nil {
+		t.Fatalf("failed to update post: %v", err)
+	}
+
+	n, err := s.PublishDuePosts(ctx)
+	if err != nil {
+		t.Fatalf("failed to publish posts: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("published %d post(s) before publish_at, expected 0", n)
+	}
+
+	now = due.Add(time.Second)
+	n, err = s.PublishDuePosts(ctx)
+	if err != nil {
+		t.Fatalf("failed to publish posts: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("published %d post(s) after publish_at, expected 2", n)
+	}
+}
+
+func TestPublisherRunStopsOnCancel(t *testing.T) {
+	db := mustOpenDB(t)
+
+	p := NewPublisher(db)
+	p.Interval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after the context was cancelled")
+	}
+}
@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"context"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+// EnableTOTP sets up two-factor authentication for the user in one
+// transaction, so a failure can't leave TOTP on without recovery codes.
+func (u *UserService) EnableTOTP(ctx context.Context, userID int, secret string, counter int64, codeHashes []string) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE user
+		SET totp_secret = ?,
+			totp_counter = ?,
+			updated_at = ?
+		WHERE id = ?
+	`,
+		secret,
+		counter,
+		tx.now,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &journal.Error{Code: journal.ENOTFOUND, Message: "User not found"}
+	}
+
+	err = replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes and stores the
+// given ones instead.
+func (u *UserService) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ConsumeRecoveryCode deletes the recovery code, so it can't be used again.
+func (u *UserService) ConsumeRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM recovery_code WHERE user_id = ? AND code_hash = ?`, userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &journal.Error{Code: journal.ENOTFOUND, Message: "Recovery code not found"}
+	}
+
+	return tx.Commit()
+}
+
+// ConsumeTOTPCounter stores counter as the user's last used time step. The
+// update only applies to a later step, so a code can't be used twice even by
+// concurrent logins.
+func (u *UserService) ConsumeTOTPCounter(ctx context.Context, userID int, counter int64) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE user SET totp_counter = ? WHERE id = ? AND totp_counter < ?`, counter, userID, counter)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Code already used"}
+	}
+
+	return tx.Commit()
+}
+
+func (u *UserService) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var n int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM recovery_code WHERE user_id = ?`, userID).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *Tx, userID int, codeHashes []string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM recovery_code WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO recovery_code (
+				user_id,
+				code_hash,
+				created_at
+			)
+			VALUES (?,?,?)
+		`,
+			userID,
+			codeHash,
+			tx.now,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
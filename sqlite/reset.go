@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+var _ journal.PasswordResetService = (*PasswordResetService)(nil)
+
+type PasswordResetService struct {
+	db *DB
+}
+
+func NewPasswordResetService(db *DB) *PasswordResetService {
+	return &PasswordResetService{
+		db: db,
+	}
+}
+
+func (s *PasswordResetService) CreatePasswordReset(ctx context.Context, reset *journal.PasswordReset) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	reset.CreatedAt = tx.now
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO password_reset (
+			user_id,
+			token_hash,
+			created_at,
+			expires_at
+		)
+		VALUES (?,?,?,?)
+	`,
+		reset.UserID,
+		reset.TokenHash,
+		reset.CreatedAt,
+		reset.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	reset.ID = int(id)
+
+	return tx.Commit()
+}
+
+// ConsumePasswordReset marks the password reset as used and returns it. Any
+// other pending reset for the same user is consumed as well, so older emails
+// stop working once the password is changed.
+func (s *PasswordResetService) ConsumePasswordReset(ctx context.Context, tokenHash string) (*journal.PasswordReset, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	resets, n, err := findPasswordResets(ctx, tx, &journal.PasswordResetFilter{TokenHash: &tokenHash})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 || !resets[0].IsValid(tx.now) {
+		return nil, &journal.Error{Code: journal.ENOTAUTHORIZED, Message: "Invalid or expired password reset"}
+	}
+	reset := resets[0]
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE password_reset
+		SET consumed_at = ?
+		WHERE user_id = ?
+		AND consumed_at IS NULL
+	`,
+		tx.now,
+		reset.UserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	consumedAt := tx.now
+	reset.ConsumedAt = &consumedAt
+
+	return reset, tx.Commit()
+}
+
+func (s *PasswordResetService) FindPasswordResets(ctx context.Context, filter *journal.PasswordResetFilter) ([]*journal.PasswordReset, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findPasswordResets(ctx, tx, filter)
+}
+
+func findPasswordResets(ctx context.Context, tx *Tx, filter *journal.PasswordResetFilter) ([]*journal.PasswordReset, int, error) {
+	// where and args should always be mutate together
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := filter.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+	if v := filter.TokenHash; v != nil {
+		where, args = append(where, "token_hash = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+		    id,
+		    user_id,
+		    token_hash,
+		    created_at,
+		    expires_at,
+		    consumed_at,
+		    COUNT(*) OVER()
+		FROM password_reset
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		`+formatLimitAndOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var n int
+	resets := make([]*journal.PasswordReset, 0)
+	for rows.Next() {
+		var reset journal.PasswordReset
+		var consumedAt sql.NullTime
+		if err := rows.Scan(
+			&reset.ID,
+			&reset.UserID,
+			&reset.TokenHash,
+			&reset.CreatedAt,
+			&reset.ExpiresAt,
+			&consumedAt,
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		if consumedAt.Valid {
+			reset.ConsumedAt = &consumedAt.Time
+		}
+		resets = append(resets, &reset)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return resets, n, nil
+}
@@ -0,0 +1,134 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+func (j *JournalService) FindPostRevisions(ctx context.Context, filter *journal.PostRevisionFilter) ([]*journal.PostRevision, int, error) {
+	tx, err := j.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findPostRevisions(ctx, tx, filter)
+}
+
+func (j *JournalService) FindPostRevisionByID(ctx context.Context, id int) (*journal.PostRevision, error) {
+	tx, err := j.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	revisions, n, err := findPostRevisions(ctx, tx, &journal.PostRevisionFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, &journal.Error{Code: journal.ENOTFOUND, Message: "Revision not found"}
+	}
+
+	return revisions[0], nil
+}
+
+// createPostRevision saves the current title and content of post.
+func createPostRevision(ctx context.Context, tx *Tx, post *journal.Post) error {
+	return createRevision(ctx, tx, postRevisionTable, post.ID, post.Title, post.Content, post.UpdatedAt)
+}
+
+func findPostRevisions(ctx context.Context, tx *Tx, filter *journal.PostRevisionFilter) ([]*journal.PostRevision, int, error) {
+	revisions := make([]*journal.PostRevision, 0)
+	n, err := findRevisions(ctx, tx, postRevisionTable, filter.ID, filter.PostID, filter.Limit, filter.Offset, func() []interface{} {
+		var revision journal.PostRevision
+		revisions = append(revisions, &revision)
+		return []interface{}{&revision.ID, &revision.PostID, &revision.Title, &revision.Content, &revision.CreatedAt}
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return revisions, n, nil
+}
+
+// revisionTable is a table keeping previous versions of posts or pages,
+// which only differ in the column referencing their owner.
+type revisionTable struct {
+	name  string
+	owner string
+}
+
+var (
+	postRevisionTable = revisionTable{name: "post_revision", owner: "post_id"}
+	pageRevisionTable = revisionTable{name: "page_revision", owner: "page_id"}
+)
+
+// createRevision saves a version of the post or page ownerID. The revision
+// is dated with the time that version was last written.
+func createRevision(ctx context.Context, tx *Tx, table revisionTable, ownerID int, title, content string, updatedAt time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO `+table.name+` (
+			`+table.owner+`,
+			title,
+			content,
+			created_at
+		)
+		VALUES (?,?,?,?)
+	`,
+		ownerID,
+		title,
+		content,
+		updatedAt,
+	)
+	return err
+}
+
+// findRevisions queries the revisions in table, newest first. For every row
+// it scans into the fields returned by next: the ID, owner ID, title,
+// content and creation time of a new revision.
+func findRevisions(ctx context.Context, tx *Tx, table revisionTable, id, ownerID *int, limit, offset int, next func() []interface{}) (int, error) {
+	// where and args should always be mutate together
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := id; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := ownerID; v != nil {
+		where, args = append(where, table.owner+" = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+		    id,
+		    `+table.owner+`,
+		    title,
+		    content,
+		    created_at,
+		    COUNT(*) OVER()
+		FROM `+table.name+`
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		`+formatLimitAndOffset(limit, offset),
+		args...,
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var n int
+	for rows.Next() {
+		if err := rows.Scan(append(next(), &n)...); err != nil {
+			return 0, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
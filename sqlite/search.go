@@ -0,0 +1,223 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+var _ journal.SearchService = (*SearchService)(nil)
+
+type SearchService struct {
+	db *DB
+}
+
+func NewSearchService(db *DB) *SearchService {
+	return &SearchService{
+		db: db,
+	}
+}
+
+func (s *SearchService) Search(ctx context.Context, filter *journal.SearchFilter) ([]*journal.SearchResult, error) {
+	query := formatMatchQuery(filter.Query)
+	if query == "" {
+		return nil, &journal.Error{Code: journal.EBADINPUT, Message: "Missing search query"}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if !s.db.fts5 {
+		return searchLike(ctx, tx, filter)
+	}
+
+	// bm25() returns lower values for better matches
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+		    ?,
+		    posts.permalink,
+		    posts.title,
+		    snippet(post_fts, 1, ?, ?, '…', 24),
+		    bm25(post_fts) AS rank
+		FROM post_fts
+		JOIN posts ON posts.id = post_fts.rowid
+		WHERE post_fts MATCH ?
+		AND posts.status = ?
+		UNION ALL
+		SELECT
+		    ?,
+		    page.name,
+		    page.title,
+		    snippet(page_fts, 1, ?, ?, '…', 24),
+		    bm25(page_fts) AS rank
+		FROM page_fts
+		JOIN page ON page.id = page_fts.rowid
+		WHERE page_fts MATCH ?
+		ORDER BY rank ASC
+		`+formatLimitAndOffset(filter.Limit, filter.Offset),
+		journal.SearchResultPost,
+		journal.SearchHighlightStart,
+		journal.SearchHighlightEnd,
+		query,
+		journal.PostStatusPublished,
+		journal.SearchResultPage,
+		journal.SearchHighlightStart,
+		journal.SearchHighlightEnd,
+		query,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*journal.SearchResult, 0)
+	for rows.Next() {
+		var result journal.SearchResult
+		if err := rows.Scan(
+			&result.Type,
+			&result.Name,
+			&result.Title,
+			&result.Snippet,
+			&result.Rank,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, &result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// formatMatchQuery turns free text into an FTS5 query that matches documents
+// containing every term. Terms are quoted so user input can't use the FTS5
+// query syntax.
+func formatMatchQuery(text string) string {
+	terms := strings.Fields(text)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
+
+// searchLike is the fallback used when SQLite lacks FTS5. It matches
+// documents containing every term, newest first, and builds the snippets
+// itself.
+func searchLike(ctx context.Context, tx *Tx, filter *journal.SearchFilter) ([]*journal.SearchResult, error) {
+	terms := strings.Fields(filter.Query)
+
+	// where and args should always be mutate together
+	postWhere, pageWhere := []string{"posts.status = ?"}, []string{"1 = 1"}
+	args := []interface{}{journal.SearchResultPost, journal.PostStatusPublished}
+	for _, term := range terms {
+		postWhere = append(postWhere, `(posts.title LIKE ? ESCAPE '\' OR posts.content LIKE ? ESCAPE '\')`)
+		pattern := "%" + escapeLike(term) + "%"
+		args = append(args, pattern, pattern)
+	}
+	args = append(args, journal.SearchResultPage)
+	for _, term := range terms {
+		pageWhere = append(pageWhere, `(page.title LIKE ? ESCAPE '\' OR page.content LIKE ? ESCAPE '\')`)
+		pattern := "%" + escapeLike(term) + "%"
+		args = append(args, pattern, pattern)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT type, name, title, content FROM (
+			SELECT ? AS type, permalink AS name, title, content, updated_at
+			FROM posts
+			WHERE `+strings.Join(postWhere, " AND ")+`
+			UNION ALL
+			SELECT ?, name, title, content, updated_at
+			FROM page
+			WHERE `+strings.Join(pageWhere, " AND ")+`
+		)
+		ORDER BY updated_at DESC
+		`+formatLimitAndOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*journal.SearchResult, 0)
+	for rows.Next() {
+		var result journal.SearchResult
+		var content string
+		if err := rows.Scan(
+			&result.Type,
+			&result.Name,
+			&result.Title,
+			&content,
+		); err != nil {
+			return nil, err
+		}
+		result.Snippet = likeSnippet(content, terms)
+		results = append(results, &result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// likeSnippet returns the words of content around the first matched term,
+// with every matched word wrapped in the highlight markers.
+func likeSnippet(content string, terms []string) string {
+	const snippetWords = 24
+
+	words := strings.Fields(content)
+	matches := func(word string) bool {
+		for _, term := range terms {
+			if strings.Contains(strings.ToLower(word), strings.ToLower(term)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	start := 0
+	for i, word := range words {
+		if matches(word) {
+			start = i - snippetWords/2
+			break
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetWords
+	if end > len(words) {
+		end = len(words)
+	}
+
+	snippet := make([]string, 0, end-start)
+	for _, word := range words[start:end] {
+		if matches(word) {
+			word = journal.SearchHighlightStart + word + journal.SearchHighlightEnd
+		}
+		snippet = append(snippet, word)
+	}
+
+	text := strings.Join(snippet, " ")
+	if start > 0 {
+		text = "…" + text
+	}
+	if end < len(words) {
+		text += "…"
+	}
+	return text
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, which must then use
+// ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+func TestSearch(t *testing.T) {
+	db := mustOpenDB(t)
+	ctx := context.Background()
+
+	posts := NewJournalService(db)
+	for _, post := range []*journal.Post{
+		{Permalink: "garden", Title: "Garden", Content: "Planted tomatoes and basil this weekend.", Status: journal.PostStatusPublished},
+		{Permalink: "discount", Title: "Discount", Content: "Everything is 100% off.", Status: journal.PostStatusPublished},
+		{Permalink: "draft", Title: "Draft", Content: "More tomatoes, not ready yet.", Status: journal.PostStatusDraft},
+	} {
+		err := posts.CreatePost(ctx, post)
+		if err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+	}
+
+	err := NewPageService(db).CreatePage(ctx, &journal.Page{Name: "about", Content: "I grow tomatoes."})
+	if err != nil {
+		t.Fatalf("failed to create page: %v", err)
+	}
+
+	s := NewSearchService(db)
+	results, err := s.Search(ctx, &journal.SearchFilter{Query: "TOMATOES"})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+
+	// Drafts never show up
+	found := make(map[string]bool)
+	for _, result := range results {
+		found[result.Type+"/"+result.Name] = true
+		if !strings.Contains(result.Snippet, journal.SearchHighlightStart+"tomatoes") {
+			t.Errorf("got snippet %q, expected the match to be highlighted", result.Snippet)
+		}
+	}
+	if len(results) != 2 || !found["post/garden"] || !found["page/about"] {
+		t.Errorf("got results %v, expected post/garden and page/about", found)
+	}
+
+	// Every term must match
+	results, err = s.Search(ctx, &journal.SearchFilter{Query: "tomatoes potatoes"})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("got %d results, expected none", len(results))
+	}
+
+	// LIKE wildcards in the query are matched literally
+	if !db.fts5 {
+		results, err = s.Search(ctx, &journal.SearchFilter{Query: "%"})
+		if err != nil {
+			t.Fatalf("failed to search: %v", err)
+		}
+		if len(results) != 1 || results[0].Name != "discount" {
+			t.Errorf("got %d results searching for %%, expected only post/discount", len(results))
+		}
+	}
+}
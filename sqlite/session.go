@@ -0,0 +1,241 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+var _ journal.SessionService = (*SessionService)(nil)
+
+type SessionService struct {
+	db *DB
+}
+
+func NewSessionService(db *DB) *SessionService {
+	return &SessionService{
+		db: db,
+	}
+}
+
+func (s *SessionService) CreateSession(ctx context.Context, session *journal.Session) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	session.CreatedAt = tx.now
+	session.UpdatedAt = tx.now
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO session (
+			id,
+			user_id,
+			data,
+			user_agent,
+			created_at,
+			updated_at,
+			expires_at
+		)
+		VALUES (?,?,?,?,?,?,?)
+	`,
+		session.ID,
+		session.UserID,
+		session.Data,
+		session.UserAgent,
+		session.CreatedAt,
+		session.UpdatedAt,
+		session.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SessionService) UpdateSession(ctx context.Context, id string, updated *journal.SessionUpdate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	session, err := findSessionByID(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if v := updated.UserID; v != nil {
+		session.UserID = *v
+	}
+
+	if v := updated.Data; v != nil {
+		session.Data = *v
+	}
+
+	if v := updated.ExpiresAt; v != nil {
+		session.ExpiresAt = *v
+	}
+
+	session.UpdatedAt = tx.now
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE session
+		SET user_id = ?,
+			data = ?,
+			updated_at = ?,
+			expires_at = ?
+		WHERE id = ?
+	`,
+		session.UserID,
+		session.Data,
+		session.UpdatedAt,
+		session.ExpiresAt.UTC(),
+		session.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SessionService) DeleteSession(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM session WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteExpiredSessions removes every session past its expiry time and
+// returns how many were removed.
+func (s *SessionService) DeleteExpiredSessions(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM session WHERE expires_at <= ?`, tx.now)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), tx.Commit()
+}
+
+// FindSessionByID returns the session with the given ID, as long as it hasn't
+// expired yet.
+func (s *SessionService) FindSessionByID(ctx context.Context, id string) (*journal.Session, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := findSessionByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !session.ExpiresAt.After(tx.now) {
+		return nil, &journal.Error{Code: journal.ENOTFOUND, Message: "Session expired"}
+	}
+
+	return session, nil
+}
+
+func (s *SessionService) FindSessions(ctx context.Context, filter *journal.SessionFilter) ([]*journal.Session, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findSessions(ctx, tx, filter)
+}
+
+func findSessionByID(ctx context.Context, tx *Tx, id string) (*journal.Session, error) {
+	sessions, n, err := findSessions(ctx, tx, &journal.SessionFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, &journal.Error{Code: journal.ENOTFOUND, Message: "Session not found"}
+	}
+
+	return sessions[0], nil
+}
+
+func findSessions(ctx context.Context, tx *Tx, filter *journal.SessionFilter) ([]*journal.Session, int, error) {
+	// where and args should always be mutate together
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := filter.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := filter.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+		    id,
+		    user_id,
+		    data,
+		    user_agent,
+		    created_at,
+		    updated_at,
+		    expires_at,
+		    COUNT(*) OVER()
+		FROM session
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY updated_at DESC
+		`+formatLimitAndOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var n int
+	sessions := make([]*journal.Session, 0)
+	for rows.Next() {
+		var session journal.Session
+		if err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.Data,
+			&session.UserAgent,
+			&session.CreatedAt,
+			&session.UpdatedAt,
+			&session.ExpiresAt,
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		sessions = append(sessions, &session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return sessions, n, nil
+}
@@ -7,11 +7,18 @@ import (
 	"fmt"
 	"io/fs"
 	"sort"
+	"strings"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
+	"k8s.io/klog/v2"
 )
 
+// fts5Marker starts migrations that need SQLite built with FTS5, which
+// go-sqlite3 only includes with -tags sqlite_fts5. They are skipped, and
+// retried on the next start, when FTS5 is missing.
+const fts5Marker = "-- requires: fts5"
+
 //go:embed migration/*.sql
 var migrationFS embed.FS
 
@@ -26,12 +33,20 @@ type DB struct {
 	cancel func()
 
 	DSN string
+
+	// fts5 reports whether SQLite was built with FTS5. Search falls back to
+	// plain LIKE matching without it.
+	fts5 bool
+
+	// Now returns the current time. It can be overridden to inject a clock.
+	Now func() time.Time
 }
 
 func NewDB(dsn string) *DB {
 	db := &DB{
 		db:  nil,
 		DSN: dsn,
+		Now: time.Now,
 	}
 	db.ctx, db.cancel = context.WithCancel(context.Background())
 	return db
@@ -53,6 +68,14 @@ func (db *DB) Open() error {
 		return fmt.Errorf("could not enable WAL: %w", err)
 	}
 
+	err = db.db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&db.fts5)
+	if err != nil {
+		return fmt.Errorf("could not check for FTS5: %w", err)
+	}
+	if !db.fts5 {
+		klog.Warning("SQLite was built without FTS5, search will be slower and unranked. Build with -tags sqlite_fts5 to enable it")
+	}
+
 	err = db.migrate()
 	if err != nil {
 		return err
@@ -77,7 +100,7 @@ func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
 
 	return &Tx{
 		Tx:  tx,
-		now: time.Now().UTC().Truncate(time.Second),
+		now: db.Now().UTC().Truncate(time.Second),
 	}, nil
 }
 
@@ -128,6 +151,10 @@ func (db *DB) migrateFile(name string) error {
 		return err
 	}
 
+	if !db.fts5 && strings.HasPrefix(string(buf), fts5Marker) {
+		return nil
+	}
+
 	if _, err := tx.Exec(string(buf)); err != nil {
 		return err
 	}
@@ -138,3 +165,14 @@ func (db *DB) migrateFile(name string) error {
 
 	return tx.Commit()
 }
+
+// parseTime parses a time read without its column type, like the result of
+// an aggregate, which the driver returns as text.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"database/sql"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func mustOpenDB(t *testing.T) *DB {
+	t.Helper()
+
+	db := NewDB(filepath.Join(t.TempDir(), "data.db"))
+	err := db.Open()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// mustMigrateTo creates a database at path with the migrations up to, and
+// including, last applied, so tests can fill it as an older version would
+// have before running the remaining migrations with Open.
+func mustMigrateTo(t *testing.T, path, last string) *sql.DB {
+	t.Helper()
+
+	db := NewDB(path)
+	conn, err := sql.Open("sqlite3", db.DSN)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	db.db = conn
+
+	err = conn.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&db.fts5)
+	if err != nil {
+		t.Fatalf("failed to check for FTS5: %v", err)
+	}
+	_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS migrations (name TEXT PRIMARY KEY);`)
+	if err != nil {
+		t.Fatalf("failed to create migrations table: %v", err)
+	}
+
+	names, err := fs.Glob(migrationFS, "migration/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name > "migration/"+last+".sql" {
+			break
+		}
+		err = db.migrateFile(name)
+		if err != nil {
+			t.Fatalf("failed to execute migration %q: %v", name, err)
+		}
+	}
+
+	return conn
+}
+
+func TestMigratePageNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	conn := mustMigrateTo(t, path, "0000000014")
+
+	// Saving a page used to insert a new row every time
+	for _, page := range []struct{ name, content string }{
+		{"about", "First about"},
+		{"contact", "Contact"},
+		{"about", "Second about"},
+		{"uses", "Uses"},
+	} {
+		_, err := conn.Exec(`INSERT INTO page (name, content, created_at, updated_at) VALUES (?, ?, datetime(), datetime())`, page.name, page.content)
+		if err != nil {
+			t.Fatalf("failed to insert page: %v", err)
+		}
+	}
+	conn.Close()
+
+	db := NewDB(path)
+	err := db.Open()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.db.Query(`SELECT name, title, content FROM page ORDER BY name`)
+	if err != nil {
+		t.Fatalf("failed to query pages: %v", err)
+	}
+	defer rows.Close()
+
+	type page struct {
+		name, title, content string
+	}
+	var pages []page
+	for rows.Next() {
+		var p page
+		if err := rows.Scan(&p.name, &p.title, &p.content); err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, p)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the latest version of each page is kept, titled after its name
+	expected := []page{
+		{"about", "About", "Second about"},
+		{"contact", "Contact", "Contact"},
+		{"uses", "Uses", "Uses"},
+	}
+	if len(pages) != len(expected) {
+		t.Fatalf("got pages %+v, expected %+v", pages, expected)
+	}
+	for i := range pages {
+		if pages[i] != expected[i] {
+			t.Errorf("got page %+v, expected %+v", pages[i], expected[i])
+		}
+	}
+
+	_, err = db.db.Exec(`INSERT INTO page (name, content) VALUES ('about', 'Duplicate')`)
+	if err == nil {
+		t.Error("inserted a duplicate page name")
+	}
+}
+
+func TestMigrateLowerEmails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	conn := mustMigrateTo(t, path, "0000000021")
+
+	for _, email := range []string{"Ann@Example.com", "bob@example.com", "Bob@Example.com"} {
+		_, err := conn.Exec(`INSERT INTO user (api_key, name, email, password) VALUES ('', 'User', ?, '')`, email)
+		if err != nil {
+			t.Fatalf("failed to insert user: %v", err)
+		}
+	}
+	conn.Close()
+
+	db := NewDB(path)
+	err := db.Open()
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var emails []string
+	rows, err := db.db.Query(`SELECT email FROM user ORDER BY id`)
+	if err != nil {
+		t.Fatalf("failed to query users: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			t.Fatal(err)
+		}
+		emails = append(emails, email)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lowering Bob@Example.com would clash with bob@example.com
+	expected := []string{"ann@example.com", "bob@example.com", "Bob@Example.com"}
+	if !reflect.DeepEqual(emails, expected) {
+		t.Errorf("got emails %v, expected %v", emails, expected)
+	}
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+// replacePostTags sets the tags of a post to exactly the given names.
+func replacePostTags(ctx context.Context, tx *Tx, postID int, tags []string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM post_tag WHERE post_id = ?`, postID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range tags {
+		_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO tag (name) VALUES (?)`, name)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO post_tag (post_id, tag_id)
+			SELECT ?, id FROM tag WHERE name = ?
+		`,
+			postID,
+			name,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Drop tags that are no longer attached to any post
+	_, err = tx.ExecContext(ctx, `DELETE FROM tag WHERE id NOT IN (SELECT tag_id FROM post_tag)`)
+	return err
+}
+
+// attachPostTags fills the Tags field of each post.
+func attachPostTags(ctx context.Context, tx *Tx, posts []*journal.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*journal.Post, len(posts))
+	placeholders, args := make([]string, 0, len(posts)), make([]interface{}, 0, len(posts))
+	for _, post := range posts {
+		post.Tags = []string{}
+		byID[post.ID] = post
+		placeholders, args = append(placeholders, "?"), append(args, post.ID)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+		    post_tag.post_id,
+		    tag.name
+		FROM post_tag
+		JOIN tag ON tag.id = post_tag.tag_id
+		WHERE post_tag.post_id IN (`+strings.Join(placeholders, ",")+`)
+		ORDER BY tag.name ASC
+		`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		var name string
+		if err := rows.Scan(&postID, &name); err != nil {
+			return err
+		}
+		if post, ok := byID[postID]; ok {
+			post.Tags = append(post.Tags, name)
+		}
+	}
+
+	return rows.Err()
+}
+
+func findTags(ctx context.Context, tx *Tx) ([]*journal.Tag, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+		    tag.name,
+		    COUNT(*)
+		FROM tag
+		JOIN post_tag ON post_tag.tag_id = tag.id
+		JOIN posts ON posts.id = post_tag.post_id
+		WHERE posts.status = ?
+		GROUP BY tag.id
+		ORDER BY tag.name ASC
+		`,
+		journal.PostStatusPublished,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]*journal.Tag, 0)
+	for rows.Next() {
+		var tag journal.Tag
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+func TestPostTags(t *testing.T) {
+	db := mustOpenDB(t)
+	ctx := context.Background()
+	s := NewJournalService(db)
+
+	for _, post := range []*journal.Post{
+		{Permalink: "first", Title: "First", Content: "Content", Status: journal.PostStatusPublished, Tags: []string{"go", "sqlite"}},
+		{Permalink: "second", Title: "Second", Content: "Content", Status: journal.PostStatusPublished, Tags: []string{"go"}},
+		{Permalink: "draft", Title: "Draft", Content: "Content", Status: journal.PostStatusDraft, Tags: []string{"go", "secret"}},
+	} {
+		err := s.CreatePost(ctx, post)
+		if err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+	}
+
+	post, err := s.FindPostByPermalink(ctx, "first")
+	if err != nil {
+		t.Fatalf("failed to find post: %v", err)
+	}
+	if expected := []string{"go", "sqlite"}; !reflect.DeepEqual(post.Tags, expected) {
+		t.Errorf("got tags %v, expected %v", post.Tags, expected)
+	}
+
+	// Drafts are neither listed nor counted
+	for tag, expected := range map[string]int{"go": 2, "sqlite": 1, "secret": 0} {
+		if n := countPublishedWithTag(t, s, tag); n != expected {
+			t.Errorf("got %d posts tagged %s, expected %d", n, tag, expected)
+		}
+	}
+
+	tags, err := s.FindTags(ctx)
+	if err != nil {
+		t.Fatalf("failed to find tags: %v", err)
+	}
+	expected := []*journal.Tag{{Name: "go", Count: 2}, {Name: "sqlite", Count: 1}}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("got tag cloud %s, expected %s", formatTags(tags), formatTags(expected))
+	}
+
+	// Tags no post carries anymore are dropped
+	none := []string{}
+	err = s.UpdatePost(ctx, "first", &journal.PostUpdate{Tags: &none})
+	if err != nil {
+		t.Fatalf("failed to update post: %v", err)
+	}
+	tags, err = s.FindTags(ctx)
+	if err != nil {
+		t.Fatalf("failed to find tags: %v", err)
+	}
+	expected = []*journal.Tag{{Name: "go", Count: 1}}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("got tag cloud %s after removing a tag, expected %s", formatTags(tags), formatTags(expected))
+	}
+}
+
+func countPublishedWithTag(t *testing.T, s *JournalService, tag string) int {
+	t.Helper()
+
+	status := journal.PostStatusPublished
+	_, n, err := s.FindPosts(context.Background(), &journal.PostFilter{Status: &status, Tag: &tag})
+	if err != nil {
+		t.Fatalf("failed to find posts tagged %s: %v", tag, err)
+	}
+	return n
+}
+
+func formatTags(tags []*journal.Tag) string {
+	s := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		s = append(s, fmt.Sprintf("%s:%d", tag.Name, tag.Count))
+	}
+	return fmt.Sprint(s)
+}
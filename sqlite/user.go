@@ -26,36 +26,33 @@ func (u *UserService) CreateUser(ctx context.Context, user *journal.User) error
 	}
 	defer tx.Rollback()
 
-	user.CreatedAt = tx.now
-	user.UpdatedAt = tx.now
+	err = createUser(ctx, tx, user)
+	if err != nil {
+		return err
+	}
 
-	result, err := tx.ExecContext(ctx, `
-		INSERT INTO user (
-			api_key,
-			name,
-			email,
-			password,
-			created_at,
-			updated_at
-		)
-		VALUES (?,?,?,?,?,?)
-	`,
-		user.APIKey,
-		user.Name,
-		user.Email,
-		user.Password,
-		user.CreatedAt,
-		user.UpdatedAt,
-	)
+	return tx.Commit()
+}
+
+// CreateUserWithInvitation creates the user and consumes the invitation in
+// the same transaction, so the invitation is only used up if the user is
+// created.
+func (u *UserService) CreateUserWithInvitation(ctx context.Context, user *journal.User, tokenHash string) error {
+	tx, err := u.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	id, err := result.LastInsertId()
+	err = consumeInvitation(ctx, tx, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	err = createUser(ctx, tx, user)
 	if err != nil {
 		return err
 	}
-	user.ID = int(id)
 
 	return tx.Commit()
 }
@@ -76,14 +73,44 @@ func (u *UserService) UpdateUser(ctx context.Context, id int, updated *journal.U
 		user.Name = *v
 	}
 
-	if v := updated.Email; v != nil {
-		user.Email = *v
+	if v := updated.Email; v != nil && lowerEmail(*v) != user.Email {
+		err = checkEmailAvailable(ctx, tx, *v)
+		if err != nil {
+			return err
+		}
+		user.Email = lowerEmail(*v)
 	}
 
 	if v := updated.Password; v != nil {
 		user.Password = *v
 	}
 
+	if v := updated.APIKey; v != nil {
+		user.APIKey = *v
+	}
+
+	if v := updated.Role; v != nil {
+		user.Role = *v
+	}
+
+	// Codes from a new secret start over
+	if v := updated.TOTPSecret; v != nil && *v != user.TOTPSecret {
+		user.TOTPSecret = *v
+		user.TOTPCounter = 0
+	}
+
+	if v := updated.Disabled; v != nil {
+		user.Disabled = *v
+	}
+
+	// Someone must be left to administer the site
+	if user.Role != journal.RoleAdmin || user.Disabled {
+		err = checkOtherAdmins(ctx, tx, user.ID)
+		if err != nil {
+			return err
+		}
+	}
+
 	user.UpdatedAt = tx.now
 
 	_, err = tx.ExecContext(ctx, `
@@ -91,12 +118,22 @@ func (u *UserService) UpdateUser(ctx context.Context, id int, updated *journal.U
         SET name = ?,
 			email = ?,
 			password = ?,
+			api_key = ?,
+			role = ?,
+			totp_secret = ?,
+			totp_counter = ?,
+			disabled = ?,
 			updated_at = ?
 		WHERE id = ?
 	`,
 		user.Name,
 		user.Email,
 		user.Password,
+		user.APIKey,
+		user.Role,
+		user.TOTPSecret,
+		user.TOTPCounter,
+		user.Disabled,
 		user.UpdatedAt,
 		user.ID,
 	)
@@ -119,33 +156,50 @@ func (u *UserService) DeleteUser(ctx context.Context, id int) error {
 		return err
 	}
 
-	// TODO: check if user is the same as the one in context
+	// Users may only delete themselves, unless they are an admin
+	current := journal.UserFromContext(ctx)
+	if current == nil || (current.ID != user.ID && !current.HasRole(journal.RoleAdmin)) {
+		return &journal.Error{Code: journal.EFORBIDDEN, Message: "You are not allowed to delete this user"}
+	}
+
+	// Someone must be left to administer the site
+	err = checkOtherAdmins(ctx, tx, user.ID)
+	if err != nil {
+		return err
+	}
 
 	_, err = tx.ExecContext(ctx, `DELETE FROM user WHERE id = ?`, user.ID)
 	if err != nil {
 		return err
 	}
 
-	return tx.Commit()
-}
+	err = replaceRecoveryCodes(ctx, tx, user.ID, nil)
+	if err != nil {
+		return err
+	}
 
-func (u *UserService) FindUsers(ctx context.Context) ([]*journal.User, error) {
-	tx, err := u.db.BeginTx(ctx, nil)
+	_, err = tx.ExecContext(ctx, `DELETE FROM password_reset WHERE user_id = ?`, user.ID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer tx.Rollback()
 
-	users, n, err := findUsers(ctx, tx, &journal.UserFilter{})
+	// Sign the user out everywhere
+	_, err = tx.ExecContext(ctx, `DELETE FROM session WHERE user_id = ?`, user.ID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if n == 0 {
-		return nil, &journal.Error{Code: journal.ENOTFOUND, Message: "There are no users available"}
+	return tx.Commit()
+}
+
+func (u *UserService) FindUsers(ctx context.Context, filter *journal.UserFilter) ([]*journal.User, int, error) {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
 	}
+	defer tx.Rollback()
 
-	return users, nil
+	return findUsers(ctx, tx, filter)
 }
 
 func (u *UserService) FindUserByID(ctx context.Context, id int) (*journal.User, error) {
@@ -178,6 +232,122 @@ func (u *UserService) FindUserByEmail(ctx context.Context, email string) (*journ
 	return user, err
 }
 
+func (u *UserService) FindUserByAPIKey(ctx context.Context, apiKey string) (*journal.User, error) {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// An empty key means the user has no key, so it must never match
+	if apiKey == "" {
+		return nil, &journal.Error{Code: journal.ENOTFOUND, Message: "User not found"}
+	}
+
+	users, n, err := findUsers(ctx, tx, &journal.UserFilter{APIKey: &apiKey})
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, &journal.Error{Code: journal.ENOTFOUND, Message: "User not found"}
+	}
+
+	return users[0], nil
+}
+
+func createUser(ctx context.Context, tx *Tx, user *journal.User) error {
+	user.Email = lowerEmail(user.Email)
+
+	err := checkEmailAvailable(ctx, tx, user.Email)
+	if err != nil {
+		return err
+	}
+
+	user.CreatedAt = tx.now
+	user.UpdatedAt = tx.now
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO user (
+			api_key,
+			name,
+			email,
+			password,
+			role,
+			created_at,
+			updated_at
+		)
+		VALUES (?,?,?,?,?,?,?)
+	`,
+		user.APIKey,
+		user.Name,
+		user.Email,
+		user.Password,
+		user.Role,
+		user.CreatedAt,
+		user.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	user.ID = int(id)
+
+	return nil
+}
+
+// checkOtherAdmins returns an error if the user is the last admin able to
+// sign in, before they are deleted, disabled or lose their role.
+func checkOtherAdmins(ctx context.Context, tx *Tx, id int) error {
+	user, err := findUserByID(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if user.Role != journal.RoleAdmin || user.Disabled {
+		return nil
+	}
+
+	var admins int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM user
+		WHERE role = ?
+		AND disabled = 0
+		AND id != ?
+	`,
+		journal.RoleAdmin,
+		id,
+	).Scan(&admins)
+	if err != nil {
+		return err
+	}
+	if admins == 0 {
+		return &journal.Error{Code: journal.EBADINPUT, Message: "The last admin can't be removed"}
+	}
+	return nil
+}
+
+// lowerEmail is how emails are stored and looked up, so that an address
+// matches its account whatever its case.
+func lowerEmail(email string) string {
+	return strings.ToLower(email)
+}
+
+// checkEmailAvailable returns an error if another user already has email.
+func checkEmailAvailable(ctx context.Context, tx *Tx, email string) error {
+	_, err := findUserByEmail(ctx, tx, email)
+	if err == nil {
+		return &journal.Error{Code: journal.EBADINPUT, Message: "Email already in use"}
+	} else if journal.ErrorCode(err) != journal.ENOTFOUND {
+		return err
+	}
+	return nil
+}
+
 func findUserByID(ctx context.Context, tx *Tx, id int) (*journal.User, error) {
 	users, n, err := findUsers(ctx, tx, &journal.UserFilter{ID: &id})
 	if err != nil {
@@ -211,7 +381,13 @@ func findUsers(ctx context.Context, tx *Tx, filter *journal.UserFilter) ([]*jour
 		where, args = append(where, "id = ?"), append(args, *v)
 	}
 	if v := filter.Email; v != nil {
-		where, args = append(where, "email = ?"), append(args, *v)
+		where, args = append(where, "email = ?"), append(args, lowerEmail(*v))
+	}
+	if v := filter.APIKey; v != nil {
+		where, args = append(where, "api_key = ?"), append(args, *v)
+	}
+	if v := filter.Search; v != nil {
+		where, args = append(where, `email LIKE ? ESCAPE '\'`), append(args, "%"+escapeLike(*v)+"%")
 	}
 
 	rows, err := tx.QueryContext(ctx, `
@@ -221,6 +397,10 @@ func findUsers(ctx context.Context, tx *Tx, filter *journal.UserFilter) ([]*jour
 		    name,
 		    email,
 		    password,
+		    role,
+		    totp_secret,
+		    totp_counter,
+		    disabled,
 		    created_at,
 		    updated_at,
 		    COUNT(*) OVER()
@@ -245,6 +425,10 @@ func findUsers(ctx context.Context, tx *Tx, filter *journal.UserFilter) ([]*jour
 			&user.Name,
 			&user.Email,
 			&user.Password,
+			&user.Role,
+			&user.TOTPSecret,
+			&user.TOTPCounter,
+			&user.Disabled,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 			&n,
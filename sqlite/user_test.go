@@ -0,0 +1,187 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	journal "github.com/bertinatto/journal3"
+)
+
+func TestCreateUserWithInvitation(t *testing.T) {
+	db := mustOpenDB(t)
+	ctx := context.Background()
+
+	users := NewUserService(db)
+	owner := &journal.User{Name: "Owner", Email: "owner@example.com", Password: "hash", Role: journal.RoleAdmin}
+	err := users.CreateUser(ctx, owner)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	invitations := NewInvitationService(db)
+	err = invitations.CreateInvitation(ctx, &journal.Invitation{
+		TokenHash: "token",
+		CreatedBy: owner.ID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create invitation: %v", err)
+	}
+
+	// An email already in use must not burn the invitation
+	taken := &journal.User{Name: "Taken", Email: owner.Email, Password: "hash", Role: journal.RoleReader}
+	err = users.CreateUserWithInvitation(ctx, taken, "token")
+	if err == nil {
+		t.Fatal("created a user with an email already in use")
+	}
+
+	user := &journal.User{Name: "Guest", Email: "guest@example.com", Password: "hash", Role: journal.RoleReader}
+	err = users.CreateUserWithInvitation(ctx, user, "token")
+	if err != nil {
+		t.Fatalf("failed to create user with invitation: %v", err)
+	}
+
+	other := &journal.User{Name: "Other", Email: "other@example.com", Password: "hash", Role: journal.RoleReader}
+	err = users.CreateUserWithInvitation(ctx, other, "token")
+	if code := journal.ErrorCode(err); code != journal.ENOTAUTHORIZED {
+		t.Fatalf("got error code %q reusing the invitation, expected %q", code, journal.ENOTAUTHORIZED)
+	}
+	_, err = users.FindUserByEmail(ctx, other.Email)
+	if code := journal.ErrorCode(err); code != journal.ENOTFOUND {
+		t.Errorf("got error code %q looking up the user, expected it not to be created", code)
+	}
+}
+
+func TestConsumeTOTPCounter(t *testing.T) {
+	db := mustOpenDB(t)
+	ctx := context.Background()
+
+	users := NewUserService(db)
+	user := &journal.User{Name: "User", Email: "user@example.com", Password: "hash", Role: journal.RoleAdmin}
+	err := users.CreateUser(ctx, user)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	err = users.ConsumeTOTPCounter(ctx, user.ID, 100)
+	if err != nil {
+		t.Fatalf("failed to consume counter: %v", err)
+	}
+
+	for _, counter := range []int64{100, 99} {
+		err = users.ConsumeTOTPCounter(ctx, user.ID, counter)
+		if code := journal.ErrorCode(err); code != journal.ENOTAUTHORIZED {
+			t.Errorf("got error code %q consuming counter %d, expected %q", code, counter, journal.ENOTAUTHORIZED)
+		}
+	}
+
+	err = users.ConsumeTOTPCounter(ctx, user.ID, 101)
+	if err != nil {
+		t.Fatalf("failed to consume a later counter: %v", err)
+	}
+
+	// A new secret starts its codes over
+	secret := "SECRET"
+	err = users.UpdateUser(ctx, user.ID, &journal.UserUpdate{TOTPSecret: &secret})
+	if err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+	user, err = users.FindUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	if user.TOTPCounter != 0 {
+		t.Errorf("got counter %d after changing the secret, expected 0", user.TOTPCounter)
+	}
+}
+
+func TestEnableTOTP(t *testing.T) {
+	db := mustOpenDB(t)
+	ctx := context.Background()
+
+	users := NewUserService(db)
+	user := &journal.User{Name: "User", Email: "user@example.com", Password: "hash", Role: journal.RoleAdmin}
+	err := users.CreateUser(ctx, user)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	err = users.ReplaceRecoveryCodes(ctx, user.ID, []string{"old"})
+	if err != nil {
+		t.Fatalf("failed to create recovery codes: %v", err)
+	}
+
+	err = users.EnableTOTP(ctx, user.ID, "SECRET", 100, []string{"new1", "new2"})
+	if err != nil {
+		t.Fatalf("failed to enable TOTP: %v", err)
+	}
+
+	user, err = users.FindUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	if user.TOTPSecret != "SECRET" || user.TOTPCounter != 100 {
+		t.Errorf("got secret %q and counter %d, expected %q and 100", user.TOTPSecret, user.TOTPCounter, "SECRET")
+	}
+	if n, err := users.CountRecoveryCodes(ctx, user.ID); err != nil || n != 2 {
+		t.Errorf("got %d recovery codes (%v), expected 2", n, err)
+	}
+	if err := users.ConsumeRecoveryCode(ctx, user.ID, "old"); journal.ErrorCode(err) != journal.ENOTFOUND {
+		t.Errorf("got error %v using an old recovery code, expected it to be gone", err)
+	}
+
+	// The code used to enroll can't be used again
+	err = users.ConsumeTOTPCounter(ctx, user.ID, 100)
+	if code := journal.ErrorCode(err); code != journal.ENOTAUTHORIZED {
+		t.Errorf("got error code %q reusing the enrollment code, expected %q", code, journal.ENOTAUTHORIZED)
+	}
+
+	err = users.EnableTOTP(ctx, user.ID+1, "SECRET", 100, []string{"other"})
+	if code := journal.ErrorCode(err); code != journal.ENOTFOUND {
+		t.Errorf("got error code %q for an unknown user, expected %q", code, journal.ENOTFOUND)
+	}
+	if n, err := users.CountRecoveryCodes(ctx, user.ID+1); err != nil || n != 0 {
+		t.Errorf("got %d recovery codes (%v) for an unknown user, expected none", n, err)
+	}
+}
+
+func TestUserEmailCase(t *testing.T) {
+	db := mustOpenDB(t)
+	ctx := context.Background()
+
+	users := NewUserService(db)
+	user := &journal.User{Name: "Ann", Email: "Ann@Example.com", Password: "hash", Role: journal.RoleAdmin}
+	err := users.CreateUser(ctx, user)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if user.Email != "ann@example.com" {
+		t.Errorf("got email %q, expected it in lower case", user.Email)
+	}
+
+	found, err := users.FindUserByEmail(ctx, "ANN@example.COM")
+	if err != nil {
+		t.Fatalf("failed to find user by email: %v", err)
+	}
+	if found.ID != user.ID {
+		t.Errorf("found user %d, expected %d", found.ID, user.ID)
+	}
+
+	err = users.CreateUser(ctx, &journal.User{Name: "Other", Email: "ann@EXAMPLE.com", Password: "hash", Role: journal.RoleReader})
+	if code := journal.ErrorCode(err); code != journal.EBADINPUT {
+		t.Errorf("got error code %q reusing the email in another case, expected %q", code, journal.EBADINPUT)
+	}
+
+	email := "Annie@Example.com"
+	err = users.UpdateUser(ctx, user.ID, &journal.UserUpdate{Email: &email})
+	if err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+	found, err = users.FindUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	if found.Email != "annie@example.com" {
+		t.Errorf("got email %q after updating, expected it in lower case", found.Email)
+	}
+}
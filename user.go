@@ -3,25 +3,91 @@ package journal
 import (
 	"context"
 	"fmt"
+	"net/mail"
 	"strings"
 	"time"
+	"unicode"
 )
 
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// roleRanks orders roles so that each role has the permissions of the roles
+// ranked below it.
+var roleRanks = map[string]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// IsValidRole reports whether role is one of the known roles.
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// User is an account able to sign in. APIKey holds the hash of the user's
+// API key, or is empty when the user has no key. Likewise, TOTPSecret is
+// empty unless the user enabled two-factor authentication, and TOTPCounter
+// is the time step of the last code they used. Disabled users can't sign in
+// nor use their API key.
 type User struct {
-	ID        int       `json:"id"`
-	APIKey    string    `json:"api_key"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  string    `json:"password"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID          int       `json:"id"`
+	APIKey      string    `json:"-"`
+	Name        string    `json:"name"`
+	Email       string    `json:"email"`
+	Password    string    `json:"-"`
+	Role        string    `json:"role"`
+	TOTPSecret  string    `json:"-"`
+	TOTPCounter int64     `json:"-"`
+	Disabled    bool      `json:"disabled"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// HasTOTP reports whether the user must provide a one-time code to sign in.
+func (u *User) HasTOTP() bool {
+	return u.TOTPSecret != ""
+}
+
+// HasRole reports whether the user has at least the permissions of role.
+func (u *User) HasRole(role string) bool {
+	return IsValidRole(role) && roleRanks[u.Role] >= roleRanks[role]
 }
 
 func (u *User) Validate() error {
-	if len(u.Password) < 6 {
+	if !IsValidRole(u.Role) {
+		return fmt.Errorf("invalid role %q", u.Role)
+	}
+	if err := ValidatePassword(u.Password); err != nil {
+		return err
+	}
+	if err := ValidateEmail(u.Email); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ValidatePassword checks that password is acceptable for an account.
+func ValidatePassword(password string) error {
+	if len(password) < 6 {
 		return fmt.Errorf("password must be at least 6 char long")
 	}
-	if !strings.Contains(u.Email, "@") {
+	return nil
+}
+
+// ValidateEmail checks that email is a bare email address, like
+// "jane@example.com". Names, angle brackets and control characters are
+// refused, since the address ends up in email headers.
+func ValidateEmail(email string) error {
+	if strings.IndexFunc(email, unicode.IsControl) >= 0 {
+		return fmt.Errorf("invalid email address")
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
 		return fmt.Errorf("invalid email address")
 	}
 	return nil
@@ -30,21 +96,46 @@ func (u *User) Validate() error {
 type UserFilter struct {
 	ID     *int    `json:"id"`
 	Email  *string `json:"email"`
+	APIKey *string `json:"-"`
 	Offset int     `json:"offset"`
 	Limit  int     `json:"limit"`
+
+	// Search matches users whose email contains it
+	Search *string `json:"search"`
 }
 
 type UserUpdate struct {
-	Name     *string `json:"name"`
-	Email    *string `json:"email"`
-	Password *string `json:"password"`
+	Name       *string `json:"name"`
+	Email      *string `json:"email"`
+	Password   *string `json:"password"`
+	APIKey     *string `json:"-"`
+	Role       *string `json:"role"`
+	TOTPSecret *string `json:"-"`
+	Disabled   *bool   `json:"disabled"`
 }
 
 type UserService interface {
 	CreateUser(ctx context.Context, user *User) (err error)
+	// CreateUserWithInvitation creates the user and consumes the invitation
+	// with the given token hash, or does neither.
+	CreateUserWithInvitation(ctx context.Context, user *User, tokenHash string) (err error)
 	UpdateUser(ctx context.Context, id int, updated *UserUpdate) (err error)
 	DeleteUser(ctx context.Context, id int) (err error)
-	FindUsers(ctx context.Context) (users []*User, err error)
+	FindUsers(ctx context.Context, filter *UserFilter) (users []*User, n int, err error)
 	FindUserByID(ctx context.Context, id int) (user *User, err error)
 	FindUserByEmail(ctx context.Context, email string) (user *User, err error)
+	FindUserByAPIKey(ctx context.Context, apiKey string) (user *User, err error)
+
+	// EnableTOTP stores the user's TOTP secret along with the time step of
+	// the code used to confirm it and new recovery codes, or none of them.
+	EnableTOTP(ctx context.Context, userID int, secret string, counter int64, codeHashes []string) (err error)
+
+	// Recovery codes let users sign in when they lose their TOTP device.
+	// Only their hashes are stored, and each can be used once.
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) (err error)
+	ConsumeRecoveryCode(ctx context.Context, userID int, codeHash string) (err error)
+	// ConsumeTOTPCounter records that the user signed in with the code of the
+	// given time step, failing if that or a later step was already used.
+	ConsumeTOTPCounter(ctx context.Context, userID int, counter int64) (err error)
+	CountRecoveryCodes(ctx context.Context, userID int) (n int, err error)
 }
@@ -0,0 +1,29 @@
+package journal
+
+import "testing"
+
+func TestValidateEmail(t *testing.T) {
+	for _, tc := range []struct {
+		email string
+		valid bool
+	}{
+		{"jane@example.com", true},
+		{"jane.doe+journal@mail.example.com", true},
+		{"", false},
+		{"jane", false},
+		{"jane@", false},
+		{"@example.com", false},
+		{"Jane <jane@example.com>", false},
+		{"<jane@example.com>", false},
+		{" jane@example.com", false},
+		{"jane@example.com\r\nBcc: eve@example.com", false},
+		{"jane@example.com\n", false},
+		{"\"jane\r\n\"@example.com", false},
+		{"jane@example.com, eve@example.com", false},
+	} {
+		err := ValidateEmail(tc.email)
+		if valid := err == nil; valid != tc.valid {
+			t.Errorf("got valid %t for %q, expected %t", valid, tc.email, tc.valid)
+		}
+	}
+}